@@ -7,9 +7,7 @@ import (
 
 // Config definition for yaml configuration
 type Config struct {
-	App      watcher.Config
-	Web      notifiers.WebConfig
-	PostMark notifiers.PostMarkConfig
-	Telegram notifiers.TelegramConfig
-	Slack    notifiers.SlackConfig
+	App       watcher.Config
+	Web       notifiers.WebConfig
+	Notifiers []notifiers.Config
 }
@@ -89,16 +89,16 @@ func main() {
 		ns = append(ns, notifier)
 
 	}
-	if conf.PostMark.Active {
-		notifier := notifiers.NewPostMarkNotifier(conf.PostMark)
-		ns = append(ns, notifier)
-	}
-	if conf.Telegram.Active {
-		notifier := notifiers.NewTelegramNotifier(conf.Telegram)
-		ns = append(ns, notifier)
-	}
-	if conf.Slack.Active {
-		notifier := notifiers.NewSlackNotifier(conf.Slack)
+	names := make(map[string]bool)
+	for _, cfg := range conf.Notifiers {
+		notifier, err := notifiers.New(cfg, watcherInstance)
+		if err != nil {
+			log.Fatal().Err(err).Str("notifier", cfg.Name).Msg("Failed to create notifier")
+		}
+		if names[cfg.Name] {
+			log.Fatal().Str("notifier", cfg.Name).Msg("Duplicate notifier name")
+		}
+		names[cfg.Name] = true
 		ns = append(ns, notifier)
 	}
 	for _, notifier := range ns {
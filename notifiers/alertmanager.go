@@ -0,0 +1,155 @@
+package notifiers
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rbhz/web_watcher/watcher"
+	"github.com/rs/zerolog/log"
+)
+
+type alertmanagerAlert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       *time.Time        `json:"endsAt,omitempty"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+// AlertmanagerNotifier pushes alerts to prometheus alertmanager
+type AlertmanagerNotifier struct {
+	apiURL       string
+	alertName    string
+	dashboardURL string
+	resendPeriod time.Duration
+	// firing alerts are stored by value, so sent copies aren't changed later
+	firing map[string]alertmanagerAlert
+	mux    sync.Mutex
+	baseNotifier
+}
+
+func (n *AlertmanagerNotifier) newAlert(u watcher.URL, errText string, startsAt time.Time) alertmanagerAlert {
+	alert := alertmanagerAlert{
+		Labels: map[string]string{
+			"alertname": n.alertName,
+			"monitor":   u.Name,
+			"url":       u.Link,
+			"severity":  u.Severity,
+		},
+		Annotations: map[string]string{
+			"summary": u.Name + ": " + errText,
+			"error":   errText,
+			"status":  strconv.Itoa(u.Status),
+		},
+		StartsAt: startsAt,
+	}
+	if len(u.Tags) != 0 {
+		alert.Labels["tags"] = strings.Join(u.Tags, ",")
+	}
+	if u.Group != "" {
+		alert.Labels["group"] = u.Group
+	}
+	if n.dashboardURL != "" {
+		alert.GeneratorURL = monitorLink(n.dashboardURL, u)
+	}
+	return alert
+}
+
+// Notify starts alert when url goes down & ends it on recovery,
+// acknowledgements are skipped as alertmanager has silences instead
+func (n *AlertmanagerNotifier) Notify(update watcher.URLUpdate) {
+	if !checkStatusChange(update) || update.Acked() {
+		return
+	}
+	link := update.New.Link
+	n.mux.Lock()
+	if update.Error() != nil {
+		n.firing[link] = n.alert(update)
+	} else {
+		delete(n.firing, link)
+	}
+	n.mux.Unlock()
+	n.dispatch([]watcher.URLUpdate{update})
+}
+
+// alert returns alert of failed url or ended alert of recovered one,
+// alert starts when url went down, so retried alerts keep the same start
+func (n *AlertmanagerNotifier) alert(update watcher.URLUpdate) alertmanagerAlert {
+	if errText := update.Error(); errText != nil {
+		return n.newAlert(update.New, *errText, downSince(update.New))
+	}
+	old := watcher.URLUpdate{New: update.Old}
+	errText := ""
+	if text := old.Error(); text != nil {
+		errText = *text
+	}
+	alert := n.newAlert(update.Old, errText, downSince(update.Old))
+	endsAt := update.Created
+	alert.EndsAt = &endsAt
+	return alert
+}
+
+// downSince returns start of url outage, last change for states stored without it
+func downSince(u watcher.URL) time.Time {
+	if u.DownSince.IsZero() {
+		return u.LastChange
+	}
+	return u.DownSince
+}
+
+// sendAlerts sends alerts of updates from outbox message
+func (n *AlertmanagerNotifier) sendAlerts(m watcher.OutboxMessage) error {
+	alerts := make([]alertmanagerAlert, 0, len(m.Updates))
+	for _, update := range m.Updates {
+		alerts = append(alerts, n.alert(update))
+	}
+	n.log(log.Debug).Int("count", len(alerts)).Msg("sending alerts")
+	return n.postJSON(n.apiURL+"/api/v2/alerts", alerts, nil)
+}
+
+// Run resends firing alerts, so alertmanager doesn't resolve them,
+// and retries failed alerts from outbox
+func (n *AlertmanagerNotifier) Run() {
+	go n.baseNotifier.Run()
+	for range time.Tick(n.resendPeriod) {
+		n.mux.Lock()
+		alerts := make([]alertmanagerAlert, 0, len(n.firing))
+		for _, alert := range n.firing {
+			alerts = append(alerts, alert)
+		}
+		n.mux.Unlock()
+		if len(alerts) == 0 {
+			continue
+		}
+		n.log(log.Debug).Int("count", len(alerts)).Msg("resending alerts")
+		if err := n.postJSON(n.apiURL+"/api/v2/alerts", alerts, nil); err != nil {
+			n.log(log.Error).Err(err).Msg("Failed to resend alerts")
+		}
+	}
+}
+
+// NewAlertmanagerNotifier creates notifier, urls which are already down are firing from start
+func NewAlertmanagerNotifier(cfg AlertmanagerConfig, w *watcher.Watcher) *AlertmanagerNotifier {
+	if cfg.URL == "" {
+		log.Fatal().Str("notifier", cfg.Name).Msg("Specify Alertmanager url")
+	}
+	notifier := &AlertmanagerNotifier{
+		apiURL:       strings.TrimRight(cfg.URL, "/"),
+		alertName:    cfg.AlertName,
+		dashboardURL: cfg.DashboardURL,
+		resendPeriod: cfg.ResendPeriod * time.Second,
+		firing:       make(map[string]alertmanagerAlert),
+		baseNotifier: newBaseNotifier(cfg.Name, cfg.Routes),
+	}
+	for _, u := range w.GetUrls() {
+		update := watcher.URLUpdate{New: u, Old: u}
+		if errText := update.Error(); errText != nil && u.UnreachableVia == "" && notifier.Match(update) {
+			notifier.firing[u.Link] = notifier.newAlert(u, *errText, downSince(u))
+		}
+	}
+	notifier.ordered = true
+	notifier.sendFunc = notifier.sendAlerts
+	return notifier
+}
@@ -1,6 +1,9 @@
 package notifiers
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
 	"sync"
 	"time"
 
@@ -9,41 +12,281 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// deliveryLog records delivery attempts of notifiers
+type deliveryLog interface {
+	RecordDelivery(d watcher.Delivery)
+}
+
+// outboxPollPeriod is how often notifiers check outbox for messages to retry
+const outboxPollPeriod = 5 * time.Second
+
+// baseNotifier contains notifier name, routing rules & delivery of messages
+type baseNotifier struct {
+	name       string
+	router     *watcher.Router
+	deliveries deliveryLog
+	outbox     outbox
+	// targets are recipients which deliveries are tracked separately,
+	// notifiers sending each message once leave it empty
+	targets []string
+	// ordered notifiers send state changes, so messages of url are delivered
+	// in order of creation & new ones wait while older one is retried
+	ordered   bool
+	outboxMux *sync.Mutex
+	// splitFunc splits updates into separately delivered messages,
+	// so retries don't repeat parts which were sent
+	splitFunc func([]watcher.URLUpdate) [][]watcher.URLUpdate
+	sendFunc  func(watcher.OutboxMessage) error
+}
+
+func newBaseNotifier(name string, routes []watcher.Route) baseNotifier {
+	router, err := watcher.NewRouter(routes)
+	if err != nil {
+		log.Fatal().Err(err).Str("notifier", name).Msg("Invalid routing rules")
+	}
+	return baseNotifier{name: name, router: router, outboxMux: &sync.Mutex{}}
+}
+
+func (n *baseNotifier) log(level func() *zerolog.Event) *zerolog.Event {
+	return level().Str("notifier", n.name)
+}
+
+func (n *baseNotifier) setDeliveryLog(l deliveryLog) {
+	n.deliveries = l
+}
+
+func (n *baseNotifier) setOutbox(o outbox) {
+	n.outbox = o
+}
+
+// dispatch stores updates in outbox for each target & sends them,
+// failed messages are retried by sendOutbox, without outbox they are sent once
+func (n *baseNotifier) dispatch(updates []watcher.URLUpdate) {
+	targets := n.targets
+	if len(targets) == 0 {
+		targets = []string{""}
+	}
+	batches := [][]watcher.URLUpdate{updates}
+	if n.splitFunc != nil {
+		batches = n.splitFunc(updates)
+	}
+	for _, batch := range batches {
+		for _, target := range targets {
+			if n.outbox == nil {
+				n.send(watcher.OutboxMessage{Notifier: n.name, Target: target, Updates: batch, Created: time.Now()})
+				continue
+			}
+			m, err := n.outbox.EnqueueMessage(n.name, target, batch)
+			if err != nil {
+				n.log(log.Error).Err(err).Msg("Failed to store message in outbox, sending directly")
+				n.send(m)
+				continue
+			}
+			if !n.ordered && n.outbox.ClaimMessage(m) {
+				n.attempt(m)
+			}
+		}
+	}
+	if n.ordered {
+		// new messages are sent after older messages of the same urls
+		n.sendOutbox()
+	}
+}
+
+// send sends message without retries
+func (n *baseNotifier) send(m watcher.OutboxMessage) {
+	if err := n.sendFunc(m); err != nil {
+		n.log(log.Error).Err(err).Str("target", m.Target).Msg("Failed to send message")
+	}
+}
+
+// attempt sends message from outbox, stores result & reports if it's delivered
+func (n *baseNotifier) attempt(m watcher.OutboxMessage) bool {
+	if err := n.sendFunc(m); err != nil {
+		n.log(log.Warn).Err(err).Int64("id", m.ID).Str("target", m.Target).Int("attempt", m.Attempts+1).
+			Msg("Failed to deliver message")
+		n.outbox.MessageFailed(m, err)
+		return false
+	}
+	n.outbox.MessageDelivered(m)
+	return true
+}
+
+// messageURLs returns keys of urls of message target
+func messageURLs(m watcher.OutboxMessage) []string {
+	keys := make([]string, 0, len(m.Updates))
+	for _, update := range m.Updates {
+		keys = append(keys, m.Target+"\n"+update.New.Link)
+	}
+	return keys
+}
+
+// sendOutbox sends due messages from outbox, messages of ordered notifiers
+// wait until older messages of the same urls are delivered or dead
+func (n *baseNotifier) sendOutbox() {
+	if n.outbox == nil {
+		return
+	}
+	n.outboxMux.Lock()
+	defer n.outboxMux.Unlock()
+	messages, err := n.outbox.PendingMessages(n.name)
+	if err != nil {
+		n.log(log.Error).Err(err).Msg("Failed to get messages from outbox")
+		return
+	}
+	now := time.Now()
+	blocked := make(map[string]bool)
+	for _, m := range messages {
+		keys := messageURLs(m)
+		ready := !m.NextAttempt.After(now)
+		if n.ordered {
+			for _, key := range keys {
+				ready = ready && !blocked[key]
+			}
+		}
+		if ready && n.outbox.ClaimMessage(m) && n.attempt(m) {
+			continue
+		}
+		for _, key := range keys {
+			blocked[key] = true
+		}
+	}
+}
+
+// Run retries failed messages from outbox
+func (n *baseNotifier) Run() {
+	n.log(log.Info).Msg("Notifier started")
+	for range time.Tick(outboxPollPeriod) {
+		n.sendOutbox()
+	}
+}
+
+// recordDelivery stores delivery attempt if delivery log is set
+func (n *baseNotifier) recordDelivery(target string, payload []byte, code int, started time.Time, err error) {
+	if n.deliveries == nil {
+		return
+	}
+	hash := sha256.Sum256(payload)
+	d := watcher.Delivery{
+		Notifier:     n.name,
+		Time:         started,
+		Target:       target,
+		PayloadHash:  hex.EncodeToString(hash[:]),
+		ResponseCode: code,
+		Latency:      time.Since(started),
+	}
+	if err != nil {
+		d.Error = err.Error()
+	}
+	n.deliveries.RecordDelivery(d)
+}
+
+// Name returns notifier name used in escalation policies
+func (n *baseNotifier) Name() string {
+	return n.name
+}
+
+// Match checks notifier routing rules
+func (n *baseNotifier) Match(update watcher.URLUpdate) bool {
+	return n.router.Match(update)
+}
+
+// acknowledger acknowledges incidents & signs acknowledgement links
+type acknowledger interface {
+	Acknowledge(link, by string) (watcher.URL, error)
+	AckToken(u watcher.URL) string
+}
+
+// outbox persists messages of notifiers until they are delivered
+type outbox interface {
+	EnqueueMessage(notifier, target string, updates []watcher.URLUpdate) (watcher.OutboxMessage, error)
+	ClaimMessage(m watcher.OutboxMessage) bool
+	PendingMessages(notifier string) ([]watcher.OutboxMessage, error)
+	MessageDelivered(m watcher.OutboxMessage)
+	MessageFailed(m watcher.OutboxMessage, err error)
+}
+
 type baseMessageNotifier struct {
-	name          string
+	baseNotifier
 	messagePeriod time.Duration
+	templates     *messageTemplates
 	updates       []watcher.URLUpdate
 	mux           sync.Mutex
-	sendFunc      func(string)
-}
-
-func (n *baseMessageNotifier) log(level func() *zerolog.Event) *zerolog.Event {
-	return level().Str("notifier", n.name)
+	reminders     *reminders
+	acks          acknowledger
+	// filter is checked after routes by notifiers accepting only some updates
+	filter func(watcher.URLUpdate) bool
+	// deliverFunc sends rendered message, it's set by notifiers using templates
+	deliverFunc func(message) error
 }
 
 func (n *baseMessageNotifier) Notify(update watcher.URLUpdate) {
-	if checkStatusChange(update) {
+	if checkStatusChange(update) && (n.filter == nil || n.filter(update)) {
+		n.reminders.track(update)
 		n.mux.Lock()
 		n.updates = append(n.updates, update)
 		n.mux.Unlock()
 	}
 }
 
-// Run sends message each n seconds
+func (n *baseMessageNotifier) setAcknowledger(a acknowledger) {
+	n.acks = a
+	if n.templates != nil {
+		n.templates.acks = a
+	}
+}
+
+func (n *baseMessageNotifier) setURLStates(s urlStates) {
+	if n.reminders != nil {
+		n.reminders.states = s
+	}
+}
+
+// Run sends message each n seconds with reminders of urls which are still down,
+// messages are stored in outbox if it's set & retried until delivered
 func (n *baseMessageNotifier) Run() {
 	n.log(log.Info).Msg("Notifier started")
+	n.reminders.restore(func(update watcher.URLUpdate) bool {
+		return n.Match(update) && (n.filter == nil || n.filter(update))
+	})
 	for range time.Tick(n.messagePeriod * time.Second) {
 		n.log(log.Debug).Msg("Checking updates")
 		n.mux.Lock()
-		if count := len(n.updates); count == 0 {
-			n.log(log.Debug).Msg("Updates not found")
-			n.mux.Unlock()
-		} else {
-			n.log(log.Debug).Int("count", count).Msg("Sending updates")
-			message := getMessage(n.updates)
-			n.updates = make([]watcher.URLUpdate, 0)
-			n.mux.Unlock()
-			n.sendFunc(message)
+		updates := n.updates
+		n.updates = make([]watcher.URLUpdate, 0)
+		n.mux.Unlock()
+		updates = append(updates, n.reminders.due(time.Now())...)
+		if len(updates) != 0 {
+			n.log(log.Debug).Int("count", len(updates)).Msg("Sending updates")
+			n.dispatch(updates)
+		}
+		n.sendOutbox()
+	}
+}
+
+// sendRendered renders updates with templates & delivers message to its target
+func (n *baseMessageNotifier) sendRendered(m watcher.OutboxMessage) error {
+	rendered := n.templates.message(m.Updates)
+	rendered.Target = m.Target
+	if m.ID != 0 {
+		// creation time keeps keys unique if database is recreated
+		rendered.Key = strconv.FormatInt(m.ID, 36) + "." + strconv.FormatInt(m.Created.Unix(), 36)
+	}
+	return n.deliverFunc(rendered)
+}
+
+// sendDigest renders digest with templates & delivers it to each target without retries
+func (n *baseMessageNotifier) sendDigest(d watcher.Digest) {
+	n.log(log.Info).Str("digest", d.Name).Msg("Sending digest")
+	rendered := n.templates.digest(d)
+	targets := n.targets
+	if len(targets) == 0 {
+		targets = []string{""}
+	}
+	for _, target := range targets {
+		rendered.Target = target
+		if err := n.deliverFunc(rendered); err != nil {
+			n.log(log.Error).Err(err).Str("digest", d.Name).Str("target", target).Msg("Failed to send digest")
 		}
 	}
 }
@@ -2,30 +2,112 @@ package notifiers
 
 import (
 	"bytes"
-	"fmt"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/rbhz/web_watcher/watcher"
+	"github.com/rs/zerolog/log"
 )
 
-func getMessage(updates []watcher.URLUpdate) string {
-	var message bytes.Buffer
-	for idx, update := range updates {
-		if idx != 0 {
-			message.WriteRune('\n')
+// monitorLink returns link to dashboard page of url
+func monitorLink(dashboardURL string, u watcher.URL) string {
+	return strings.TrimRight(dashboardURL, "/") + "/?url=" + url.QueryEscape(u.Link)
+}
+
+// ackLink returns signed link acknowledging url incident on dashboard
+func ackLink(dashboardURL string, u watcher.URL, token string) string {
+	query := url.Values{"url": {u.Link}, "token": {token}}
+	return strings.TrimRight(dashboardURL, "/") + "/ack?" + query.Encode()
+}
+
+// severityOrder lists severities from the most important one
+var severityOrder = []string{
+	watcher.SeverityCritical, watcher.SeverityError, watcher.SeverityWarning, watcher.SeverityInfo}
+
+// batchSeverity returns highest severity of failed urls,
+// batch containing only recoveries & acknowledgements has info severity
+func batchSeverity(updates []watcher.URLUpdate) string {
+	highest := len(severityOrder) - 1
+	for _, update := range updates {
+		if update.Error() == nil || update.Acked() {
+			continue
+		}
+		for idx, severity := range severityOrder[:highest] {
+			if update.New.Severity == severity {
+				highest = idx
+				break
+			}
 		}
-		message.WriteString(fmt.Sprintf("%v %v: ",
-			update.Created.Round(time.Second).UTC().Format("2-1-2006 2 15:04:05"), update.Old.Link))
-		if errText := update.Error(); errText != nil {
-			message.WriteString(*errText)
-		} else {
-			message.WriteString("OK")
+	}
+	return severityOrder[highest]
+}
+
+// hasFailures checks if any of updates reports failed url, acknowledgements aren't failures
+func hasFailures(updates []watcher.URLUpdate) bool {
+	for _, update := range updates {
+		if update.Error() != nil && !update.Acked() {
+			return true
 		}
 	}
-	return message.String()
+	return false
+}
+
+// priorityMap returns service priorities by severity with overrides applied
+func priorityMap(name string, defaults, overrides map[string]int) map[string]int {
+	priorities := make(map[string]int, len(defaults))
+	for severity, priority := range defaults {
+		priorities[severity] = priority
+	}
+	for severity, priority := range overrides {
+		if _, ok := defaults[severity]; !ok {
+			log.Fatal().Str("notifier", name).Str("severity", severity).Msg("Unknown severity in priorities")
+		}
+		priorities[severity] = priority
+	}
+	return priorities
+}
+
+// downtime returns time url is down or was down before recovery
+func downtime(update watcher.URLUpdate) time.Duration {
+	if since := update.New.DownSince; !since.IsZero() {
+		return update.Created.Sub(since)
+	} else if since := update.Old.DownSince; !since.IsZero() {
+		return update.Created.Sub(since)
+	}
+	return 0
+}
+
+// downtimeText describes outage duration like default message templates:
+// still down for reminders, down for escalations & repeated failures
+// and recovered after for recoveries, acknowledgements show who acked
+func downtimeText(update watcher.URLUpdate, locale watcher.Locale) string {
+	d := downtime(update)
+	switch {
+	case update.Error() == nil:
+		if d == 0 {
+			return ""
+		}
+		return locale.T("recovered_after", locale.Duration(d))
+	case update.Acked():
+		return locale.T("acked_by", update.New.AckedBy)
+	case update.Reminder != 0:
+		return locale.T("still_down", locale.Duration(d))
+	case update.Escalation != 0 || !update.Old.Good():
+		return locale.T("down_for", locale.Duration(d))
+	}
+	return ""
 }
 
 func checkStatusChange(update watcher.URLUpdate) bool {
+	if update.Acked() {
+		// incident acknowledged
+		return true
+	}
 	if update.Old.Good() != update.New.Good() {
 		// status changed
 		return true
@@ -38,3 +120,74 @@ func checkStatusChange(update watcher.URLUpdate) bool {
 	}
 	return false
 }
+
+// errNoUpdates is returned for outbox messages which updates couldn't be decoded
+var errNoUpdates = errors.New("message has no updates")
+
+// httpError is returned when service responds with unexpected status code
+type httpError struct {
+	code int
+}
+
+func (e httpError) Error() string {
+	return "unexpected response code " + strconv.Itoa(e.code)
+}
+
+// doRequest performs request, checks response status code & records delivery
+func (n *baseNotifier) doRequest(req *http.Request, body []byte) error {
+	client := &http.Client{Timeout: 5 * time.Second}
+	started := time.Now()
+	resp, err := client.Do(req)
+	code := 0
+	if err == nil {
+		resp.Body.Close()
+		code = resp.StatusCode
+		if code < 200 || code >= 300 {
+			err = httpError{code: code}
+		}
+	}
+	n.recordDelivery(urlTarget(req.URL), body, code, started, err)
+	return err
+}
+
+// urlTarget returns delivery target of url without path & query,
+// which could contain secrets, e.g. slack webhook token
+func urlTarget(u *url.URL) string {
+	return u.Scheme + "://" + u.Host
+}
+
+// postForm sends url encoded form
+func (n *baseNotifier) postForm(url string, form url.Values, headers map[string]string) error {
+	body := []byte(form.Encode())
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	return n.doRequest(req, body)
+}
+
+// postJSON sends payload encoded as json
+func (n *baseNotifier) postJSON(url string, payload interface{}, headers map[string]string) error {
+	return n.sendJSON("POST", url, payload, headers)
+}
+
+// sendJSON sends payload encoded as json using given method
+func (n *baseNotifier) sendJSON(method, url string, payload interface{}, headers map[string]string) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(method, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	return n.doRequest(req, data)
+}
@@ -1,37 +1,297 @@
 package notifiers
 
-import "time"
+import (
+	"time"
+
+	"github.com/rbhz/web_watcher/watcher"
+)
 
 // WebConfig describes web notifier confing
 type WebConfig struct {
 	Active   bool `default:"false"`
 	Port     int  `default:"8080"`
 	Profiler bool `default:"false"`
+	// Timezone & Language of web ui, app settings are used by default
+	Timezone string
+	Language string
+}
+
+// MessageTemplate describes templates of notification messages,
+// see README for data passed to templates
+type MessageTemplate struct {
+	// Template of plain text message, text/template syntax
+	Template string
+	// HTMLTemplate is used by notifiers supporting html, html/template syntax
+	HTMLTemplate string
+	// DigestTemplate & DigestHTMLTemplate render digest reports, same syntax as messages
+	DigestTemplate     string
+	DigestHTMLTemplate string
+	// DashboardURL is public address of web notifier used for links
+	DashboardURL string
+	// Timezone & Language of messages, app settings are used by default
+	Timezone string
+	Language string
 }
 
 // PostMarkConfig describes postmark notifier config
 type PostMarkConfig struct {
-	Active        bool `default:"false"`
-	APIKey        string
-	Emails        []string
-	FromEmail     string
-	Subject       string        `default:"Http checker errors"`
-	MessageText   string        `default:"Request failed for"`
-	MessagePeriod time.Duration `default:"10"`
+	Name            string
+	APIKey          string
+	Emails          []string
+	FromEmail       string
+	Subject         string        `default:"Http checker errors"`
+	MessagePeriod   time.Duration `default:"10"`
+	Routes          []watcher.Route
+	MessageTemplate `yaml:",inline"`
+	ReminderConfig  `yaml:",inline"`
 }
 
 // TelegramConfig describes telegram notifier config
 type TelegramConfig struct {
-	Active        bool `default:"false"`
-	BotToken      string
-	Users         []int64
-	MessageText   string        `default:"Request failed for"`
-	MessagePeriod time.Duration `default:"10"`
+	Name     string
+	BotToken string
+	Users    []int64
+	// Commands enables /ack command in chats of users
+	Commands        bool
+	MessagePeriod   time.Duration `default:"10"`
+	Routes          []watcher.Route
+	MessageTemplate `yaml:",inline"`
+	ReminderConfig  `yaml:",inline"`
 }
 
 // SlackConfig describes slack notifier configuration
 type SlackConfig struct {
-	Active        bool `default:"false"`
+	Name            string
+	WebHookURL      string
+	MessagePeriod   time.Duration `default:"10"`
+	Routes          []watcher.Route
+	MessageTemplate `yaml:",inline"`
+	ReminderConfig  `yaml:",inline"`
+}
+
+// WebhookConfig describes webhook notifier configuration
+type WebhookConfig struct {
+	Name   string
+	URL    string
+	Method string `default:"POST"`
+	// Body & Headers are go templates receiving .Update and .Updates,
+	// digests are sent as JSON & headers receive .Digest
+	Body            string
+	Headers         map[string]string
+	Secret          string
+	SignatureHeader string `default:"X-Signature-256"`
+	// Batch collects updates for MessagePeriod seconds before sending
+	Batch         bool
+	MessagePeriod time.Duration `default:"10"`
+	Routes        []watcher.Route
+}
+
+// SMTPConfig describes smtp notifier configuration
+type SMTPConfig struct {
+	Name string
+	Host string
+	Port int `default:"587"`
+	// TLS mode: none, starttls or tls
+	TLS             string `default:"starttls"`
+	Username        string
+	Password        string
+	From            string
+	To              []string
+	CC              []string
+	Subject         string        `default:"Http checker errors"`
+	MessagePeriod   time.Duration `default:"10"`
+	Routes          []watcher.Route
+	MessageTemplate `yaml:",inline"`
+	ReminderConfig  `yaml:",inline"`
+}
+
+// DiscordConfig describes discord notifier configuration
+type DiscordConfig struct {
+	Name          string
 	WebHookURL    string
+	Username      string
+	MessagePeriod time.Duration `default:"10"`
+	// Timezone & Language of messages, app settings are used by default
+	Timezone       string
+	Language       string
+	Routes         []watcher.Route
+	ReminderConfig `yaml:",inline"`
+}
+
+// TeamsConfig describes microsoft teams notifier configuration
+type TeamsConfig struct {
+	Name       string
+	WebHookURL string
+	Title      string `default:"Http checker"`
+	// DashboardURL is public address of web notifier used for links
+	DashboardURL  string
+	MessagePeriod time.Duration `default:"10"`
+	// Timezone & Language of messages, app settings are used by default
+	Timezone       string
+	Language       string
+	Routes         []watcher.Route
+	ReminderConfig `yaml:",inline"`
+}
+
+// PagerDutyConfig describes pagerduty notifier configuration
+type PagerDutyConfig struct {
+	Name         string
+	RoutingKey   string
+	APIURL       string `default:"https://events.pagerduty.com"`
+	DashboardURL string
+	Routes       []watcher.Route
+}
+
+// OpsgenieRule sets alert options for updates matching route
+type OpsgenieRule struct {
+	watcher.Route `yaml:",inline"`
+	Priority      string
+	Teams         []string
+	Responders    []string
+}
+
+// OpsgenieConfig describes opsgenie notifier configuration
+type OpsgenieConfig struct {
+	Name   string
+	APIKey string
+	// APIURL should be changed to https://api.eu.opsgenie.com for EU region
+	APIURL string `default:"https://api.opsgenie.com"`
+	// Priority, Teams & Responders are used if update doesn't match any of Rules
+	Priority   string `default:"P3"`
+	Teams      []string
+	Responders []string
+	Rules      []OpsgenieRule
+	Routes     []watcher.Route
+}
+
+// AlertmanagerConfig describes alertmanager notifier configuration
+type AlertmanagerConfig struct {
+	Name      string
+	URL       string
+	AlertName string `default:"WebWatcherURLDown"`
+	// ResendPeriod of firing alerts in seconds,
+	// should be less than alertmanager resolve_timeout
+	ResendPeriod time.Duration `default:"60"`
+	DashboardURL string
+	Routes       []watcher.Route
+}
+
+// MatrixConfig describes matrix notifier configuration
+type MatrixConfig struct {
+	Name            string
+	Homeserver      string
+	AccessToken     string
+	RoomID          string
+	MessagePeriod   time.Duration `default:"10"`
+	Routes          []watcher.Route
+	MessageTemplate `yaml:",inline"`
+	ReminderConfig  `yaml:",inline"`
+}
+
+// NtfyConfig describes ntfy notifier configuration
+type NtfyConfig struct {
+	Name string
+	// TopicURL is full topic address, e.g. https://ntfy.sh/my_topic
+	TopicURL string
+	Token    string
+	Title    string `default:"Http checker"`
+	Tags     []string
+	// Priorities overrides ntfy priority (1-5) by monitor severity
+	Priorities      map[string]int
+	MessagePeriod   time.Duration `default:"10"`
+	Routes          []watcher.Route
+	MessageTemplate `yaml:",inline"`
+	ReminderConfig  `yaml:",inline"`
+}
+
+// GotifyConfig describes gotify notifier configuration
+type GotifyConfig struct {
+	Name      string
+	ServerURL string
+	AppToken  string
+	Title     string `default:"Http checker"`
+	// Priorities overrides gotify priority (0-10) by monitor severity
+	Priorities      map[string]int
+	MessagePeriod   time.Duration `default:"10"`
+	Routes          []watcher.Route
+	MessageTemplate `yaml:",inline"`
+	ReminderConfig  `yaml:",inline"`
+}
+
+// PushoverConfig describes pushover notifier configuration
+type PushoverConfig struct {
+	Name    string
+	AppKey  string
+	UserKey string
+	APIURL  string `default:"https://api.pushover.net"`
+	Title   string `default:"Http checker"`
+	// Priorities overrides pushover priority (-2-2) by monitor severity
+	Priorities map[string]int
+	// Retry & Expire of emergency priority messages in seconds
+	Retry           time.Duration `default:"60"`
+	Expire          time.Duration `default:"3600"`
+	MessagePeriod   time.Duration `default:"10"`
+	Routes          []watcher.Route
+	MessageTemplate `yaml:",inline"`
+	ReminderConfig  `yaml:",inline"`
+}
+
+// SMSConfig describes sms notifier configuration,
+// only updates of critical urls are sent
+type SMSConfig struct {
+	Name       string
+	AccountSID string
+	AuthToken  string
+	// APIURL of Twilio compatible messages API
+	APIURL string `default:"https://api.twilio.com"`
+	From   string
+	To     []string
+	// MaxMessages sent to each recipient per batch
+	MaxMessages     int           `default:"3"`
+	MessagePeriod   time.Duration `default:"10"`
+	Routes          []watcher.Route
+	MessageTemplate `yaml:",inline"`
+	ReminderConfig  `yaml:",inline"`
+}
+
+// ExecConfig describes exec notifier configuration
+type ExecConfig struct {
+	Name string
+	// Command receives update (or list of updates in batch mode) as json on stdin
+	// and WW_URL, WW_STATUS, WW_ERROR, WW_CHANGE environment variables
+	// filled from update (first update of batch)
+	Command string
+	Args    []string
+	// Batch collects updates for MessagePeriod seconds before running command
+	Batch         bool
 	MessagePeriod time.Duration `default:"10"`
+	// Timeout of command in seconds
+	Timeout time.Duration `default:"10"`
+	// Concurrency limits number of simultaneously running commands
+	Concurrency int `default:"1"`
+	Routes      []watcher.Route
+}
+
+// SyslogConfig describes syslog notifier configuration
+type SyslogConfig struct {
+	Name string
+	// Network: udp, tcp or empty for local socket
+	Network string
+	// Address is host:port of remote server or path of local socket
+	Address  string
+	Facility string `default:"daemon"`
+	Hostname string
+	AppName  string `default:"web_watcher"`
+	Routes   []watcher.Route
+}
+
+// FileConfig describes file notifier configuration
+type FileConfig struct {
+	Name string
+	Path string
+	// MaxSize of file in megabytes before rotation
+	MaxSize int64 `default:"10"`
+	// MaxBackups is number of rotated files kept
+	MaxBackups int `default:"5"`
+	Routes     []watcher.Route
 }
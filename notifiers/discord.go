@@ -0,0 +1,256 @@
+package notifiers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rbhz/web_watcher/watcher"
+	"github.com/rs/zerolog/log"
+)
+
+// Discord message limits
+const (
+	discordMaxEmbeds       = 10
+	discordMaxEmbedsLength = 6000
+	discordMaxTitle        = 256
+	discordMaxDescription  = 4096
+	discordMaxFieldValue   = 1024
+	discordMaxAttempts     = 3
+)
+
+// Embed colours
+const (
+	discordColorGood        = 0x2ecc71
+	discordColorBad         = 0xe74c3c
+	discordColorUnreachable = 0x95a5a6
+)
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type discordEmbed struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	URL         string         `json:"url,omitempty"`
+	Color       int            `json:"color"`
+	Fields      []discordField `json:"fields"`
+	Timestamp   string         `json:"timestamp"`
+}
+
+func (e discordEmbed) length() int {
+	length := len(e.Title) + len(e.Description)
+	for _, field := range e.Fields {
+		length += len(field.Name) + len(field.Value)
+	}
+	return length
+}
+
+type discordMessage struct {
+	Username string         `json:"username,omitempty"`
+	Embeds   []discordEmbed `json:"embeds"`
+}
+
+// DiscordNotifier sends notifications to discord channel webhook
+type DiscordNotifier struct {
+	webHookURL string
+	username   string
+	// resetAt is guarded by mux
+	resetAt time.Time
+	baseMessageNotifier
+}
+
+func truncate(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit-1]) + "…"
+}
+
+func (n *DiscordNotifier) embed(update watcher.URLUpdate) discordEmbed {
+	url := update.New
+	locale := n.templates.locale
+	embed := discordEmbed{
+		Title:       truncate(url.Name, discordMaxTitle),
+		Description: downtimeText(update, locale),
+		Color:       discordColorGood,
+		// discord shows timestamp in time zone of reader
+		Timestamp: update.Created.In(locale.Location).Format(time.RFC3339),
+		Fields: []discordField{
+			{Name: locale.T("url"), Value: truncate(url.Link, discordMaxFieldValue)},
+			{Name: locale.T("status_code"), Value: strconv.Itoa(url.Status), Inline: true},
+			{Name: locale.T("response_time"), Value: url.ResponseTime.Round(time.Millisecond).String(), Inline: true},
+		},
+	}
+	if len(url.Link) <= discordMaxFieldValue {
+		embed.URL = url.Link
+	}
+	if errText := update.LocalizedError(locale); errText != nil {
+		embed.Color = discordColorBad
+		if url.UnreachableVia != "" {
+			embed.Color = discordColorUnreachable
+		}
+		embed.Fields = append(embed.Fields, discordField{Name: locale.T("error"), Value: truncate(*errText, discordMaxFieldValue)})
+	}
+	if count := len(update.Dependents); count != 0 {
+		embed.Fields = append(embed.Fields, discordField{
+			Name: locale.T("dependents_column"), Value: strconv.Itoa(count), Inline: true})
+	}
+	return embed
+}
+
+// splitEmbeds splits embeds into messages respecting discord limits
+func splitEmbeds(embeds []discordEmbed) (chunks [][]discordEmbed) {
+	var (
+		chunk  []discordEmbed
+		length int
+	)
+	for _, embed := range embeds {
+		if len(chunk) == discordMaxEmbeds || (len(chunk) != 0 && length+embed.length() > discordMaxEmbedsLength) {
+			chunks = append(chunks, chunk)
+			chunk, length = nil, 0
+		}
+		chunk = append(chunk, embed)
+		length += embed.length()
+	}
+	if len(chunk) != 0 {
+		chunks = append(chunks, chunk)
+	}
+	return
+}
+
+// splitUpdates groups updates so each group is sent in one message
+func (n *DiscordNotifier) splitUpdates(updates []watcher.URLUpdate) (batches [][]watcher.URLUpdate) {
+	embeds := make([]discordEmbed, 0, len(updates))
+	for _, update := range updates {
+		embeds = append(embeds, n.embed(update))
+	}
+	start := 0
+	for _, chunk := range splitEmbeds(embeds) {
+		batches = append(batches, updates[start:start+len(chunk)])
+		start += len(chunk)
+	}
+	return
+}
+
+func (n *DiscordNotifier) sendMessage(m watcher.OutboxMessage) error {
+	n.log(log.Info).Msg("sending message")
+	embeds := make([]discordEmbed, 0, len(m.Updates))
+	for _, update := range m.Updates {
+		embeds = append(embeds, n.embed(update))
+	}
+	for _, chunk := range splitEmbeds(embeds) {
+		data, err := json.Marshal(&discordMessage{Username: n.username, Embeds: chunk})
+		if err != nil {
+			return err
+		}
+		if err := n.post(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NotifyDigest sends digest report as embed with text of digest template
+func (n *DiscordNotifier) NotifyDigest(d watcher.Digest) {
+	n.log(log.Info).Str("digest", d.Name).Msg("Sending digest")
+	rendered := n.templates.digest(d)
+	embed := discordEmbed{
+		Title:       truncate(rendered.Title, discordMaxTitle),
+		Description: truncate(rendered.Text, discordMaxDescription),
+		Color:       discordColorGood,
+		Timestamp:   d.To.In(n.templates.locale.Location).Format(time.RFC3339),
+		Fields:      []discordField{},
+	}
+	if d.Incidents != 0 {
+		embed.Color = discordColorBad
+	}
+	data, err := json.Marshal(&discordMessage{Username: n.username, Embeds: []discordEmbed{embed}})
+	if err == nil {
+		err = n.post(data)
+	}
+	if err != nil {
+		n.log(log.Error).Err(err).Str("digest", d.Name).Msg("Failed to send digest")
+	}
+}
+
+// post sends message waiting for rate limits to reset
+func (n *DiscordNotifier) post(data []byte) error {
+	client := &http.Client{Timeout: 5 * time.Second}
+	for attempt := 1; ; attempt++ {
+		n.mux.Lock()
+		wait := time.Until(n.resetAt)
+		n.mux.Unlock()
+		if wait > 0 {
+			n.log(log.Debug).Dur("wait", wait).Msg("Waiting for rate limit reset")
+			time.Sleep(wait)
+		}
+		req, err := http.NewRequest("POST", n.webHookURL, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		started := time.Now()
+		resp, err := client.Do(req)
+		if err != nil {
+			n.recordDelivery(urlTarget(req.URL), data, 0, started, err)
+			return err
+		}
+		resp.Body.Close()
+		var respErr error
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			respErr = httpError{code: resp.StatusCode}
+		}
+		n.recordDelivery(urlTarget(req.URL), data, resp.StatusCode, started, respErr)
+		if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			n.setResetAt(parseSeconds(resp.Header.Get("X-RateLimit-Reset-After")))
+		}
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < discordMaxAttempts {
+			n.setResetAt(parseSeconds(resp.Header.Get("Retry-After")))
+			n.log(log.Warn).Msg("Discord rate limit exceeded")
+			continue
+		}
+		return respErr
+	}
+}
+
+// setResetAt delays next requests until rate limit resets,
+// it's shared by messages & digests sent from different goroutines
+func (n *DiscordNotifier) setResetAt(after time.Duration) {
+	n.mux.Lock()
+	n.resetAt = time.Now().Add(after)
+	n.mux.Unlock()
+}
+
+// parseSeconds parses fractional seconds header value
+func parseSeconds(value string) time.Duration {
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds < 0 {
+		return time.Second
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// NewDiscordNotifier creates notifier
+func NewDiscordNotifier(cfg DiscordConfig) *DiscordNotifier {
+	if cfg.WebHookURL == "" {
+		log.Fatal().Str("notifier", cfg.Name).Msg("Specify Discord webhook url")
+	}
+	notifier := DiscordNotifier{
+		webHookURL: cfg.WebHookURL,
+		username:   cfg.Username,
+		baseMessageNotifier: baseMessageNotifier{
+			baseNotifier:  newBaseNotifier(cfg.Name, cfg.Routes),
+			messagePeriod: cfg.MessagePeriod,
+			templates:     newMessageTemplates(cfg.Name, MessageTemplate{Timezone: cfg.Timezone, Language: cfg.Language}),
+			reminders:     newReminders(cfg.ReminderConfig)}}
+	notifier.splitFunc = notifier.splitUpdates
+	notifier.sendFunc = notifier.sendMessage
+	return &notifier
+}
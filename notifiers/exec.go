@@ -0,0 +1,134 @@
+package notifiers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rbhz/web_watcher/watcher"
+	"github.com/rs/zerolog/log"
+)
+
+// ExecNotifier runs command for each update or batch of updates
+type ExecNotifier struct {
+	command string
+	args    []string
+	batch   bool
+	timeout time.Duration
+	slots   chan struct{}
+	baseMessageNotifier
+}
+
+// Notify runs command immediately or adds update to batch,
+// both modes receive every update including content changes
+func (n *ExecNotifier) Notify(update watcher.URLUpdate) {
+	if n.batch {
+		n.mux.Lock()
+		n.updates = append(n.updates, update)
+		n.mux.Unlock()
+		return
+	}
+	n.dispatch([]watcher.URLUpdate{update})
+}
+
+// Run runs command for batches if batching is enabled & retries failed runs
+func (n *ExecNotifier) Run() {
+	if n.batch {
+		n.baseMessageNotifier.Run()
+	} else {
+		n.baseNotifier.Run()
+	}
+}
+
+// runMessage runs command for update or batch of updates from outbox
+func (n *ExecNotifier) runMessage(m watcher.OutboxMessage) error {
+	if len(m.Updates) == 0 {
+		return errNoUpdates
+	}
+	if n.batch {
+		return n.run(m.Updates, m.Updates[0])
+	}
+	return n.run(m.Updates[0], m.Updates[0])
+}
+
+// run executes command passing payload as json on stdin,
+// environment variables are filled from update
+func (n *ExecNotifier) run(payload interface{}, update watcher.URLUpdate) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		n.log(log.Error).Err(err).Msg("Failed to encode data")
+		return err
+	}
+	n.slots <- struct{}{}
+	defer func() { <-n.slots }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), n.timeout)
+	defer cancel()
+	cmd := exec.Command(n.command, n.args...)
+	startProcessGroup(cmd)
+	cmd.Env = append(os.Environ(),
+		"WW_URL="+update.New.Link,
+		"WW_STATUS="+strconv.Itoa(update.New.Status),
+		"WW_ERROR="+update.New.Err,
+		"WW_CHANGE="+strings.Join(update.ChangeNames(), ","),
+	)
+	cmd.Stdin = bytes.NewReader(data)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	n.log(log.Info).Str("url", update.New.Link).Msg("running command")
+	started := time.Now()
+	if err = cmd.Start(); err == nil {
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				killProcessGroup(cmd)
+			case <-done:
+			}
+		}()
+		err = cmd.Wait()
+		close(done)
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		err = ctx.Err()
+	}
+	code := 0
+	if cmd.ProcessState != nil {
+		code = cmd.ProcessState.ExitCode()
+	}
+	n.recordDelivery(n.command, data, code, started, err)
+	output := strings.TrimSpace(stderr.String())
+	if err != nil {
+		n.log(log.Error).Err(err).Str("url", update.New.Link).Str("stderr", output).Msg("Command failed")
+	} else if output != "" {
+		n.log(log.Info).Str("url", update.New.Link).Str("stderr", output).Msg("Command finished")
+	}
+	return err
+}
+
+// NewExecNotifier creates notifier
+func NewExecNotifier(cfg ExecConfig) *ExecNotifier {
+	if cfg.Command == "" {
+		log.Fatal().Str("notifier", cfg.Name).Msg("Specify command")
+	}
+	if cfg.Concurrency < 1 {
+		log.Fatal().Str("notifier", cfg.Name).Msg("Concurrency should be positive")
+	}
+	notifier := ExecNotifier{
+		command: cfg.Command,
+		args:    cfg.Args,
+		batch:   cfg.Batch,
+		timeout: cfg.Timeout * time.Second,
+		slots:   make(chan struct{}, cfg.Concurrency),
+		baseMessageNotifier: baseMessageNotifier{
+			baseNotifier:  newBaseNotifier(cfg.Name, cfg.Routes),
+			messagePeriod: cfg.MessagePeriod}}
+	notifier.sendFunc = notifier.runMessage
+	return &notifier
+}
@@ -0,0 +1,20 @@
+//go:build !windows
+// +build !windows
+
+package notifiers
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// startProcessGroup runs command in own process group,
+// so timeout kills children of command too
+func startProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup kills started command with its children
+func killProcessGroup(cmd *exec.Cmd) {
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
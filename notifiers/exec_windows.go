@@ -0,0 +1,11 @@
+package notifiers
+
+import "os/exec"
+
+// startProcessGroup keeps default process attributes, windows has no process groups to kill
+func startProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills started command, its children keep running
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
@@ -0,0 +1,149 @@
+package notifiers
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rbhz/web_watcher/watcher"
+	"github.com/rs/zerolog/log"
+)
+
+// event is flat description of update used by log sinks
+type event struct {
+	Time         time.Time `json:"time"`
+	URL          string    `json:"url"`
+	Name         string    `json:"name"`
+	Group        string    `json:"group,omitempty"`
+	Tags         []string  `json:"tags,omitempty"`
+	Severity     string    `json:"severity"`
+	Changes      []string  `json:"changes"`
+	Status       int       `json:"status"`
+	Error        string    `json:"error,omitempty"`
+	OldStatus    int       `json:"old_status"`
+	OldError     string    `json:"old_error,omitempty"`
+	ResponseTime float64   `json:"response_time"`
+	Unreachable  string    `json:"unreachable_via,omitempty"`
+	Dependents   []string  `json:"dependents,omitempty"`
+}
+
+func newEvent(update watcher.URLUpdate) event {
+	return event{
+		Time:         update.Created.UTC(),
+		URL:          update.New.Link,
+		Name:         update.New.Name,
+		Group:        update.New.Group,
+		Tags:         update.New.Tags,
+		Severity:     update.New.Severity,
+		Changes:      update.ChangeNames(),
+		Status:       update.New.Status,
+		Error:        update.New.Err,
+		OldStatus:    update.Old.Status,
+		OldError:     update.Old.Err,
+		ResponseTime: update.New.ResponseTime.Seconds(),
+		Unreachable:  update.New.UnreachableVia,
+		Dependents:   update.Dependents,
+	}
+}
+
+// FileNotifier appends json line per update to file, rotating it by size
+type FileNotifier struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+	mux        sync.Mutex
+	baseNotifier
+}
+
+// Notify writes update to file
+func (n *FileNotifier) Notify(update watcher.URLUpdate) {
+	n.dispatch([]watcher.URLUpdate{update})
+}
+
+// write appends events of updates from outbox message to file
+func (n *FileNotifier) write(m watcher.OutboxMessage) error {
+	var data []byte
+	for _, update := range m.Updates {
+		line, err := json.Marshal(newEvent(update))
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+	n.mux.Lock()
+	defer n.mux.Unlock()
+	if n.size+int64(len(data)) > n.maxSize && n.size != 0 {
+		if err := n.rotate(); err != nil {
+			n.log(log.Error).Err(err).Msg("Failed to rotate file")
+		}
+	}
+	if n.file == nil {
+		if err := n.open(); err != nil {
+			return err
+		}
+	}
+	started := time.Now()
+	written, err := n.file.Write(data)
+	n.size += int64(written)
+	n.recordDelivery(n.path, data, 0, started, err)
+	return err
+}
+
+func (n *FileNotifier) open() error {
+	file, err := os.OpenFile(n.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	n.file, n.size = file, info.Size()
+	return nil
+}
+
+// rotate renames file to path.1 shifting older backups, oldest one is removed
+func (n *FileNotifier) rotate() error {
+	if n.file != nil {
+		n.file.Close()
+		n.file = nil
+	}
+	n.size = 0
+	if n.maxBackups == 0 {
+		return os.Remove(n.path)
+	}
+	backup := func(idx int) string { return n.path + "." + strconv.Itoa(idx) }
+	os.Remove(backup(n.maxBackups))
+	for idx := n.maxBackups - 1; idx > 0; idx-- {
+		if err := os.Rename(backup(idx), backup(idx+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(n.path, backup(1))
+}
+
+// NewFileNotifier creates notifier
+func NewFileNotifier(cfg FileConfig) *FileNotifier {
+	if cfg.Path == "" {
+		log.Fatal().Str("notifier", cfg.Name).Msg("Specify file path")
+	}
+	if cfg.MaxSize <= 0 || cfg.MaxBackups < 0 {
+		log.Fatal().Str("notifier", cfg.Name).Msg("Invalid file rotation settings")
+	}
+	notifier := &FileNotifier{
+		path:         cfg.Path,
+		maxSize:      cfg.MaxSize * 1024 * 1024,
+		maxBackups:   cfg.MaxBackups,
+		baseNotifier: newBaseNotifier(cfg.Name, cfg.Routes),
+	}
+	if err := notifier.open(); err != nil {
+		log.Fatal().Err(err).Str("notifier", cfg.Name).Msg("Failed to open file")
+	}
+	notifier.sendFunc = notifier.write
+	return notifier
+}
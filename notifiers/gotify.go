@@ -0,0 +1,67 @@
+package notifiers
+
+import (
+	"strings"
+
+	"github.com/rbhz/web_watcher/watcher"
+	"github.com/rs/zerolog/log"
+)
+
+// gotifyPriorities maps severities to gotify priorities: 0 (min) - 10 (max)
+var gotifyPriorities = map[string]int{
+	watcher.SeverityCritical: 10,
+	watcher.SeverityError:    8,
+	watcher.SeverityWarning:  5,
+	watcher.SeverityInfo:     2,
+}
+
+type gotifyMessage struct {
+	Title    string `json:"title"`
+	Message  string `json:"message"`
+	Priority int    `json:"priority"`
+}
+
+// GotifyNotifier sends notifications to gotify server
+type GotifyNotifier struct {
+	serverURL  string
+	appToken   string
+	title      string
+	priorities map[string]int
+	baseMessageNotifier
+}
+
+func (n *GotifyNotifier) deliver(m message) error {
+	n.log(log.Info).Msg("sending message")
+	data := gotifyMessage{
+		Title:    m.title(n.title),
+		Message:  m.Text,
+		Priority: n.priorities[m.Severity],
+	}
+	headers := map[string]string{"X-Gotify-Key": n.appToken}
+	return n.postJSON(n.serverURL+"/message", data, headers)
+}
+
+// NotifyDigest sends digest report
+func (n *GotifyNotifier) NotifyDigest(d watcher.Digest) {
+	n.sendDigest(d)
+}
+
+// NewGotifyNotifier creates notifier
+func NewGotifyNotifier(cfg GotifyConfig) *GotifyNotifier {
+	if cfg.ServerURL == "" || cfg.AppToken == "" {
+		log.Fatal().Str("notifier", cfg.Name).Msg("Specify Gotify server url & app token")
+	}
+	notifier := GotifyNotifier{
+		serverURL:  strings.TrimRight(cfg.ServerURL, "/"),
+		appToken:   cfg.AppToken,
+		title:      cfg.Title,
+		priorities: priorityMap(cfg.Name, gotifyPriorities, cfg.Priorities),
+		baseMessageNotifier: baseMessageNotifier{
+			baseNotifier:  newBaseNotifier(cfg.Name, cfg.Routes),
+			messagePeriod: cfg.MessagePeriod,
+			reminders:     newReminders(cfg.ReminderConfig),
+			templates:     newMessageTemplates(cfg.Name, cfg.MessageTemplate)}}
+	notifier.deliverFunc = notifier.deliver
+	notifier.sendFunc = notifier.sendRendered
+	return &notifier
+}
@@ -0,0 +1,76 @@
+package notifiers
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/rbhz/web_watcher/watcher"
+	"github.com/rs/zerolog/log"
+)
+
+type matrixMessage struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format"`
+	FormattedBody string `json:"formatted_body"`
+}
+
+// MatrixNotifier sends notifications to matrix room
+type MatrixNotifier struct {
+	homeserver  string
+	accessToken string
+	roomID      string
+	txnCounter  uint64
+	baseMessageNotifier
+}
+
+// txnID returns transaction id of message, it's derived from outbox message,
+// so homeserver ignores retries of already delivered message
+func (n *MatrixNotifier) txnID(m message) string {
+	if m.Key != "" {
+		return "web_watcher.outbox." + m.Key
+	}
+	counter := atomic.AddUint64(&n.txnCounter, 1)
+	return "web_watcher." + strconv.FormatInt(time.Now().UnixNano(), 36) + "." + strconv.FormatUint(counter, 36)
+}
+
+func (n *MatrixNotifier) deliver(m message) error {
+	n.log(log.Info).Msg("sending message")
+	endpoint := n.homeserver + "/_matrix/client/v3/rooms/" + url.PathEscape(n.roomID) +
+		"/send/m.room.message/" + url.PathEscape(n.txnID(m))
+	message := matrixMessage{
+		MsgType:       "m.text",
+		Body:          m.Text,
+		Format:        "org.matrix.custom.html",
+		FormattedBody: m.HTML,
+	}
+	headers := map[string]string{"Authorization": "Bearer " + n.accessToken}
+	return n.sendJSON("PUT", endpoint, message, headers)
+}
+
+// NotifyDigest sends digest report
+func (n *MatrixNotifier) NotifyDigest(d watcher.Digest) {
+	n.sendDigest(d)
+}
+
+// NewMatrixNotifier creates notifier
+func NewMatrixNotifier(cfg MatrixConfig) *MatrixNotifier {
+	if cfg.Homeserver == "" || cfg.AccessToken == "" || cfg.RoomID == "" {
+		log.Fatal().Str("notifier", cfg.Name).Msg("Specify Matrix homeserver, access token & room id")
+	}
+	notifier := MatrixNotifier{
+		homeserver:  strings.TrimRight(cfg.Homeserver, "/"),
+		accessToken: cfg.AccessToken,
+		roomID:      cfg.RoomID,
+		baseMessageNotifier: baseMessageNotifier{
+			baseNotifier:  newBaseNotifier(cfg.Name, cfg.Routes),
+			messagePeriod: cfg.MessagePeriod,
+			reminders:     newReminders(cfg.ReminderConfig),
+			templates:     newMessageTemplates(cfg.Name, cfg.MessageTemplate)}}
+	notifier.deliverFunc = notifier.deliver
+	notifier.sendFunc = notifier.sendRendered
+	return &notifier
+}
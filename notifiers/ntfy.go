@@ -0,0 +1,77 @@
+package notifiers
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rbhz/web_watcher/watcher"
+	"github.com/rs/zerolog/log"
+)
+
+const ntfyMaxMessage = 4000
+
+// ntfyPriorities maps severities to ntfy priorities: 1 (min) - 5 (max)
+var ntfyPriorities = map[string]int{
+	watcher.SeverityCritical: 5,
+	watcher.SeverityError:    4,
+	watcher.SeverityWarning:  3,
+	watcher.SeverityInfo:     2,
+}
+
+// NtfyNotifier publishes notifications to ntfy topic
+type NtfyNotifier struct {
+	topicURL   string
+	token      string
+	title      string
+	tags       []string
+	priorities map[string]int
+	baseMessageNotifier
+}
+
+func (n *NtfyNotifier) deliver(m message) error {
+	n.log(log.Info).Msg("sending message")
+	body := []byte(truncate(m.Text, ntfyMaxMessage))
+	req, err := http.NewRequest("POST", n.topicURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	tags := append([]string{"white_check_mark"}, n.tags...)
+	if m.Failed {
+		tags[0] = "warning"
+	}
+	req.Header.Set("Title", m.title(n.title))
+	req.Header.Set("Priority", strconv.Itoa(n.priorities[m.Severity]))
+	req.Header.Set("Tags", strings.Join(tags, ","))
+	if n.token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	}
+	return n.doRequest(req, body)
+}
+
+// NotifyDigest sends digest report
+func (n *NtfyNotifier) NotifyDigest(d watcher.Digest) {
+	n.sendDigest(d)
+}
+
+// NewNtfyNotifier creates notifier
+func NewNtfyNotifier(cfg NtfyConfig) *NtfyNotifier {
+	if cfg.TopicURL == "" {
+		log.Fatal().Str("notifier", cfg.Name).Msg("Specify ntfy topic url")
+	}
+	notifier := NtfyNotifier{
+		topicURL:   cfg.TopicURL,
+		token:      cfg.Token,
+		title:      cfg.Title,
+		tags:       cfg.Tags,
+		priorities: priorityMap(cfg.Name, ntfyPriorities, cfg.Priorities),
+		baseMessageNotifier: baseMessageNotifier{
+			baseNotifier:  newBaseNotifier(cfg.Name, cfg.Routes),
+			messagePeriod: cfg.MessagePeriod,
+			reminders:     newReminders(cfg.ReminderConfig),
+			templates:     newMessageTemplates(cfg.Name, cfg.MessageTemplate)}}
+	notifier.deliverFunc = notifier.deliver
+	notifier.sendFunc = notifier.sendRendered
+	return &notifier
+}
@@ -0,0 +1,161 @@
+package notifiers
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/rbhz/web_watcher/watcher"
+	"github.com/rs/zerolog/log"
+)
+
+const opsgenieMaxMessage = 130
+
+type opsgenieResponder struct {
+	Type     string `json:"type"`
+	Name     string `json:"name,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+type opsgenieAlert struct {
+	Message     string              `json:"message"`
+	Alias       string              `json:"alias"`
+	Description string              `json:"description"`
+	Responders  []opsgenieResponder `json:"responders,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Details     map[string]string   `json:"details,omitempty"`
+	Entity      string              `json:"entity"`
+	Source      string              `json:"source"`
+	Priority    string              `json:"priority"`
+}
+
+type opsgenieNote struct {
+	Note   string `json:"note"`
+	Source string `json:"source"`
+}
+
+// opsgenieRule is compiled OpsgenieRule
+type opsgenieRule struct {
+	router     *watcher.Router
+	priority   string
+	responders []opsgenieResponder
+}
+
+// OpsgenieNotifier creates, updates & closes Opsgenie alerts
+type OpsgenieNotifier struct {
+	apiURL   string
+	apiKey   string
+	rules    []opsgenieRule
+	fallback opsgenieRule
+	baseNotifier
+}
+
+func newOpsgenieRule(priority string, teams, users []string) opsgenieRule {
+	rule := opsgenieRule{priority: priority}
+	for _, team := range teams {
+		rule.responders = append(rule.responders, opsgenieResponder{Type: "team", Name: team})
+	}
+	for _, user := range users {
+		rule.responders = append(rule.responders, opsgenieResponder{Type: "user", Username: user})
+	}
+	return rule
+}
+
+// rule returns alert options for update
+func (n *OpsgenieNotifier) rule(update watcher.URLUpdate) opsgenieRule {
+	for _, rule := range n.rules {
+		if rule.router.Match(update) {
+			return rule
+		}
+	}
+	return n.fallback
+}
+
+// Notify creates alert when url goes down, adds note when error changes,
+// acknowledges alert with incident and closes alert on recovery
+func (n *OpsgenieNotifier) Notify(update watcher.URLUpdate) {
+	if checkStatusChange(update) {
+		n.dispatch([]watcher.URLUpdate{update})
+	}
+}
+
+// sendRequests sends request of each update from outbox message
+func (n *OpsgenieNotifier) sendRequests(m watcher.OutboxMessage) error {
+	headers := map[string]string{"Authorization": "GenieKey " + n.apiKey}
+	for _, update := range m.Updates {
+		path, payload := n.request(update)
+		n.log(log.Info).Str("url", update.New.Link).Str("path", path).Msg("sending request")
+		if err := n.postJSON(n.apiURL+path, payload, headers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// request returns api path & payload of update
+func (n *OpsgenieNotifier) request(update watcher.URLUpdate) (path string, payload interface{}) {
+	alias := url.PathEscape(dedupKey(update.New))
+	errText := update.Error()
+	switch {
+	case errText == nil:
+		path = "/v2/alerts/" + alias + "/close?identifierType=alias"
+		payload = opsgenieNote{Note: "Recovered", Source: "web_watcher"}
+	case update.Acked():
+		path = "/v2/alerts/" + alias + "/acknowledge?identifierType=alias"
+		payload = opsgenieNote{Note: "Acknowledged by " + update.New.AckedBy, Source: "web_watcher"}
+	case !update.Old.Good() && update.Old.UnreachableVia == "":
+		path = "/v2/alerts/" + alias + "/notes?identifierType=alias"
+		payload = opsgenieNote{Note: *errText, Source: "web_watcher"}
+	default:
+		path = "/v2/alerts"
+		payload = n.alert(update, *errText)
+	}
+	return
+}
+
+func (n *OpsgenieNotifier) alert(update watcher.URLUpdate, errText string) opsgenieAlert {
+	u := update.New
+	rule := n.rule(update)
+	return opsgenieAlert{
+		Message:     truncate(u.Name+": "+errText, opsgenieMaxMessage),
+		Alias:       dedupKey(u),
+		Description: errText,
+		Responders:  rule.responders,
+		Tags:        u.Tags,
+		Details: map[string]string{
+			"url":      u.Link,
+			"group":    u.Group,
+			"severity": u.Severity,
+		},
+		Entity:   u.Name,
+		Source:   "web_watcher",
+		Priority: rule.priority,
+	}
+}
+
+// NewOpsgenieNotifier creates notifier
+func NewOpsgenieNotifier(cfg OpsgenieConfig) *OpsgenieNotifier {
+	if cfg.APIKey == "" {
+		log.Fatal().Str("notifier", cfg.Name).Msg("Specify Opsgenie api key")
+	}
+	notifier := &OpsgenieNotifier{
+		apiURL:       strings.TrimRight(cfg.APIURL, "/"),
+		apiKey:       cfg.APIKey,
+		fallback:     newOpsgenieRule(cfg.Priority, cfg.Teams, cfg.Responders),
+		baseNotifier: newBaseNotifier(cfg.Name, cfg.Routes),
+	}
+	for _, rule := range cfg.Rules {
+		compiled := newOpsgenieRule(rule.Priority, rule.Teams, rule.Responders)
+		if compiled.priority == "" {
+			compiled.priority = cfg.Priority
+		}
+		router, err := watcher.NewRouter([]watcher.Route{rule.Route})
+		if err != nil {
+			log.Fatal().Err(err).Str("notifier", cfg.Name).Msg("Invalid Opsgenie rule")
+		}
+		compiled.router = router
+		notifier.rules = append(notifier.rules, compiled)
+	}
+	notifier.ordered = true
+	notifier.sendFunc = notifier.sendRequests
+	return notifier
+}
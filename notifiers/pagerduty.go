@@ -0,0 +1,127 @@
+package notifiers
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/rbhz/web_watcher/watcher"
+	"github.com/rs/zerolog/log"
+)
+
+// PagerDuty event actions
+const (
+	pagerDutyTrigger     = "trigger"
+	pagerDutyAcknowledge = "acknowledge"
+	pagerDutyResolve     = "resolve"
+)
+
+type pagerDutyPayload struct {
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source"`
+	Severity      string                 `json:"severity"`
+	Timestamp     string                 `json:"timestamp"`
+	Component     string                 `json:"component,omitempty"`
+	Group         string                 `json:"group,omitempty"`
+	Class         string                 `json:"class,omitempty"`
+	CustomDetails map[string]interface{} `json:"custom_details,omitempty"`
+}
+
+type pagerDutyLink struct {
+	Href string `json:"href"`
+	Text string `json:"text"`
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+	Links       []pagerDutyLink   `json:"links,omitempty"`
+}
+
+// PagerDutyNotifier triggers & resolves PagerDuty alerts via Events API v2
+type PagerDutyNotifier struct {
+	apiURL       string
+	routingKey   string
+	dashboardURL string
+	baseNotifier
+}
+
+// dedupKey returns stable alert key for url
+func dedupKey(u watcher.URL) string {
+	hash := sha1.Sum([]byte(u.Link))
+	return "web_watcher-" + hex.EncodeToString(hash[:])
+}
+
+// Notify triggers alert when url goes down, acknowledges it with incident
+// & resolves it on recovery
+func (n *PagerDutyNotifier) Notify(update watcher.URLUpdate) {
+	if checkStatusChange(update) {
+		n.dispatch([]watcher.URLUpdate{update})
+	}
+}
+
+// sendEvents sends event of each update from outbox message
+func (n *PagerDutyNotifier) sendEvents(m watcher.OutboxMessage) error {
+	for _, update := range m.Updates {
+		event := n.event(update)
+		n.log(log.Info).Str("url", update.New.Link).Str("action", event.EventAction).Msg("sending event")
+		if err := n.postJSON(n.apiURL+"/v2/enqueue", event, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *PagerDutyNotifier) event(update watcher.URLUpdate) pagerDutyEvent {
+	event := pagerDutyEvent{
+		RoutingKey:  n.routingKey,
+		EventAction: pagerDutyResolve,
+		DedupKey:    dedupKey(update.New),
+	}
+	if update.Acked() {
+		event.EventAction = pagerDutyAcknowledge
+		return event
+	}
+	if errText := update.Error(); errText != nil {
+		url := update.New
+		event.EventAction = pagerDutyTrigger
+		event.Payload = &pagerDutyPayload{
+			Summary:   url.Name + ": " + *errText,
+			Source:    url.Link,
+			Severity:  url.Severity,
+			Timestamp: update.Created.UTC().Format(time.RFC3339),
+			Component: url.Name,
+			Group:     url.Group,
+			Class:     "http check",
+			CustomDetails: map[string]interface{}{
+				"status":     url.Status,
+				"error":      url.Err,
+				"tags":       url.Tags,
+				"dependents": update.Dependents,
+			},
+		}
+		if n.dashboardURL != "" {
+			event.Links = []pagerDutyLink{{Href: monitorLink(n.dashboardURL, url), Text: "Dashboard"}}
+		}
+	}
+	return event
+}
+
+// NewPagerDutyNotifier creates notifier
+func NewPagerDutyNotifier(cfg PagerDutyConfig) *PagerDutyNotifier {
+	if cfg.RoutingKey == "" {
+		log.Fatal().Str("notifier", cfg.Name).Msg("Specify PagerDuty routing key")
+	}
+	notifier := &PagerDutyNotifier{
+		apiURL:       strings.TrimRight(cfg.APIURL, "/"),
+		routingKey:   cfg.RoutingKey,
+		dashboardURL: cfg.DashboardURL,
+		baseNotifier: newBaseNotifier(cfg.Name, cfg.Routes),
+	}
+	notifier.ordered = true
+	notifier.sendFunc = notifier.sendEvents
+	return notifier
+}
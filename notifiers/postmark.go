@@ -1,12 +1,9 @@
 package notifiers
 
 import (
-	"bytes"
-	"encoding/json"
-	"net/http"
 	"strings"
-	"time"
 
+	"github.com/rbhz/web_watcher/watcher"
 	"github.com/rs/zerolog/log"
 )
 
@@ -31,50 +28,37 @@ type PostMarkNotifier struct {
 	baseMessageNotifier
 }
 
-func (n *PostMarkNotifier) sendMessage(text string) {
+func (n *PostMarkNotifier) deliver(m message) error {
 	n.log(log.Info).Msg("sending message")
-	data, err := json.Marshal(&postmarkRequestData{
+	data := &postmarkRequestData{
 		From:     n.fromEmail,
 		To:       n.emails[0],
 		CC:       strings.Join(n.emails[1:], ","),
-		Subject:  n.subject,
-		TextBody: text,
-	})
-	if err != nil {
-		n.log(log.Error).Err(err).Msg("Failed to endode data")
-		return
+		Subject:  m.title(n.subject),
+		TextBody: m.Text,
 	}
-	req, err := http.NewRequest(
-		"POST", postMarkAPIURL, bytes.NewReader(data),
-	)
-	if err != nil {
-		n.log(log.Error).Err(err).Msg("Failed to create request obj")
-		return
-	}
-	req.Header.Add("X-Postmark-Server-Token", n.token)
-	req.Header.Add("Accept", "application/json")
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		n.log(log.Error).Err(err).Msg("Failed to perform request")
-		return
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		n.log(log.Error).Int("code", resp.StatusCode).Msg("Postmark returned invalid code")
+	headers := map[string]string{
+		"X-Postmark-Server-Token": n.token,
+		"Accept":                  "application/json",
 	}
+	return n.postJSON(postMarkAPIURL, data, headers)
+}
+
+// NotifyDigest sends digest report
+func (n *PostMarkNotifier) NotifyDigest(d watcher.Digest) {
+	n.sendDigest(d)
 }
 
 // NewPostMarkNotifier creates notifier
 func NewPostMarkNotifier(cfg PostMarkConfig) *PostMarkNotifier {
 	if len(cfg.Emails) == 0 {
-		log.Fatal().Msg("Specify Postmark emails or deactivate postmark")
+		log.Fatal().Msg("Specify Postmark emails")
 	}
 	if cfg.APIKey == "" {
-		log.Fatal().Msg("Specify Postmark token or deactivate postmark")
+		log.Fatal().Msg("Specify Postmark token")
 	}
 	if cfg.FromEmail == "" {
-		log.Fatal().Msg("Specify Postmark from address or deactivate postmark")
+		log.Fatal().Msg("Specify Postmark from address")
 	}
 	notifier := PostMarkNotifier{
 		emails:    cfg.Emails,
@@ -82,8 +66,11 @@ func NewPostMarkNotifier(cfg PostMarkConfig) *PostMarkNotifier {
 		fromEmail: cfg.FromEmail,
 		subject:   cfg.Subject,
 		baseMessageNotifier: baseMessageNotifier{
+			baseNotifier:  newBaseNotifier(cfg.Name, cfg.Routes),
 			messagePeriod: cfg.MessagePeriod,
-			name:          "postmark"}}
-	notifier.sendFunc = notifier.sendMessage
+			reminders:     newReminders(cfg.ReminderConfig),
+			templates:     newMessageTemplates(cfg.Name, cfg.MessageTemplate)}}
+	notifier.deliverFunc = notifier.deliver
+	notifier.sendFunc = notifier.sendRendered
 	return &notifier
 }
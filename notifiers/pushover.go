@@ -0,0 +1,92 @@
+package notifiers
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rbhz/web_watcher/watcher"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	pushoverMaxMessage = 1024
+	pushoverMaxTitle   = 250
+	// pushoverEmergency priority is repeated until acknowledged
+	pushoverEmergency = 2
+	// pushover limits for emergency retry & expire
+	pushoverMinRetry  = 30 * time.Second
+	pushoverMaxExpire = 3 * time.Hour
+)
+
+// pushoverPriorities maps severities to pushover priorities: -2 (lowest) - 2 (emergency)
+var pushoverPriorities = map[string]int{
+	watcher.SeverityCritical: pushoverEmergency,
+	watcher.SeverityError:    1,
+	watcher.SeverityWarning:  0,
+	watcher.SeverityInfo:     -1,
+}
+
+// PushoverNotifier sends notifications via pushover
+type PushoverNotifier struct {
+	apiURL     string
+	appKey     string
+	userKey    string
+	title      string
+	priorities map[string]int
+	retry      time.Duration
+	expire     time.Duration
+	baseMessageNotifier
+}
+
+func (n *PushoverNotifier) deliver(m message) error {
+	n.log(log.Info).Msg("sending message")
+	priority := n.priorities[m.Severity]
+	form := url.Values{
+		"token":    {n.appKey},
+		"user":     {n.userKey},
+		"title":    {truncate(m.title(n.title), pushoverMaxTitle)},
+		"message":  {truncate(m.Text, pushoverMaxMessage)},
+		"priority": {strconv.Itoa(priority)},
+	}
+	if priority == pushoverEmergency {
+		form.Set("retry", strconv.Itoa(int(n.retry/time.Second)))
+		form.Set("expire", strconv.Itoa(int(n.expire/time.Second)))
+	}
+	return n.postForm(n.apiURL+"/1/messages.json", form, nil)
+}
+
+// NotifyDigest sends digest report
+func (n *PushoverNotifier) NotifyDigest(d watcher.Digest) {
+	n.sendDigest(d)
+}
+
+// NewPushoverNotifier creates notifier
+func NewPushoverNotifier(cfg PushoverConfig) *PushoverNotifier {
+	if cfg.AppKey == "" || cfg.UserKey == "" {
+		log.Fatal().Str("notifier", cfg.Name).Msg("Specify Pushover app & user keys")
+	}
+	notifier := PushoverNotifier{
+		apiURL:     strings.TrimRight(cfg.APIURL, "/"),
+		appKey:     cfg.AppKey,
+		userKey:    cfg.UserKey,
+		title:      cfg.Title,
+		priorities: priorityMap(cfg.Name, pushoverPriorities, cfg.Priorities),
+		retry:      cfg.Retry * time.Second,
+		expire:     cfg.Expire * time.Second,
+		baseMessageNotifier: baseMessageNotifier{
+			baseNotifier:  newBaseNotifier(cfg.Name, cfg.Routes),
+			messagePeriod: cfg.MessagePeriod,
+			reminders:     newReminders(cfg.ReminderConfig),
+			templates:     newMessageTemplates(cfg.Name, cfg.MessageTemplate)}}
+	if notifier.retry < pushoverMinRetry {
+		notifier.retry = pushoverMinRetry
+	}
+	if notifier.expire > pushoverMaxExpire {
+		notifier.expire = pushoverMaxExpire
+	}
+	notifier.deliverFunc = notifier.deliver
+	notifier.sendFunc = notifier.sendRendered
+	return &notifier
+}
@@ -0,0 +1,228 @@
+package notifiers
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/jinzhu/configor"
+	"github.com/rbhz/web_watcher/watcher"
+	"gopkg.in/yaml.v2"
+)
+
+// Config describes notifier instance, type specific options are stored
+// on the same level as name & type
+type Config struct {
+	Name    string
+	Type    string
+	options map[string]interface{}
+}
+
+// UnmarshalYAML keeps all notifier options for type specific decoding
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&c.options); err != nil {
+		return err
+	}
+	var base struct{ Name, Type string }
+	if err := unmarshal(&base); err != nil {
+		return err
+	}
+	c.Name, c.Type = base.Name, base.Type
+	if c.Name == "" {
+		c.Name = c.Type
+	}
+	return nil
+}
+
+// UnmarshalJSON keeps all notifier options for type specific decoding
+func (c *Config) UnmarshalJSON(data []byte) error {
+	return yaml.Unmarshal(data, c)
+}
+
+var envNameRe = regexp.MustCompile("[^A-Za-z0-9]+")
+
+// Decode notifier options into type specific config & apply its default values
+func (c Config) Decode(out interface{}) error {
+	options := make(map[string]interface{}, len(c.options))
+	for key, value := range c.options {
+		options[key] = value
+	}
+	options["name"] = c.Name
+	data, err := yaml.Marshal(options)
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(data, out); err != nil {
+		return err
+	}
+	envPrefix := "CONFIGOR_NOTIFIER_" + strings.ToUpper(envNameRe.ReplaceAllString(c.Name, "_"))
+	return configor.New(&configor.Config{ENVPrefix: envPrefix}).Load(out)
+}
+
+type factory func(cfg Config, w *watcher.Watcher) (watcher.Notifier, error)
+
+var registry = map[string]factory{
+	"postmark": func(cfg Config, w *watcher.Watcher) (watcher.Notifier, error) {
+		var options PostMarkConfig
+		if err := cfg.Decode(&options); err != nil {
+			return nil, err
+		}
+		return NewPostMarkNotifier(options), nil
+	},
+	"telegram": func(cfg Config, w *watcher.Watcher) (watcher.Notifier, error) {
+		var options TelegramConfig
+		if err := cfg.Decode(&options); err != nil {
+			return nil, err
+		}
+		return NewTelegramNotifier(options), nil
+	},
+	"slack": func(cfg Config, w *watcher.Watcher) (watcher.Notifier, error) {
+		var options SlackConfig
+		if err := cfg.Decode(&options); err != nil {
+			return nil, err
+		}
+		return NewSlackNotifier(options), nil
+	},
+	"discord": func(cfg Config, w *watcher.Watcher) (watcher.Notifier, error) {
+		var options DiscordConfig
+		if err := cfg.Decode(&options); err != nil {
+			return nil, err
+		}
+		return NewDiscordNotifier(options), nil
+	},
+	"teams": func(cfg Config, w *watcher.Watcher) (watcher.Notifier, error) {
+		var options TeamsConfig
+		if err := cfg.Decode(&options); err != nil {
+			return nil, err
+		}
+		return NewTeamsNotifier(options), nil
+	},
+	"alertmanager": func(cfg Config, w *watcher.Watcher) (watcher.Notifier, error) {
+		var options AlertmanagerConfig
+		if err := cfg.Decode(&options); err != nil {
+			return nil, err
+		}
+		return NewAlertmanagerNotifier(options, w), nil
+	},
+	"exec": func(cfg Config, w *watcher.Watcher) (watcher.Notifier, error) {
+		var options ExecConfig
+		if err := cfg.Decode(&options); err != nil {
+			return nil, err
+		}
+		return NewExecNotifier(options), nil
+	},
+	"file": func(cfg Config, w *watcher.Watcher) (watcher.Notifier, error) {
+		var options FileConfig
+		if err := cfg.Decode(&options); err != nil {
+			return nil, err
+		}
+		return NewFileNotifier(options), nil
+	},
+	"gotify": func(cfg Config, w *watcher.Watcher) (watcher.Notifier, error) {
+		var options GotifyConfig
+		if err := cfg.Decode(&options); err != nil {
+			return nil, err
+		}
+		return NewGotifyNotifier(options), nil
+	},
+	"matrix": func(cfg Config, w *watcher.Watcher) (watcher.Notifier, error) {
+		var options MatrixConfig
+		if err := cfg.Decode(&options); err != nil {
+			return nil, err
+		}
+		return NewMatrixNotifier(options), nil
+	},
+	"ntfy": func(cfg Config, w *watcher.Watcher) (watcher.Notifier, error) {
+		var options NtfyConfig
+		if err := cfg.Decode(&options); err != nil {
+			return nil, err
+		}
+		return NewNtfyNotifier(options), nil
+	},
+	"opsgenie": func(cfg Config, w *watcher.Watcher) (watcher.Notifier, error) {
+		var options OpsgenieConfig
+		if err := cfg.Decode(&options); err != nil {
+			return nil, err
+		}
+		return NewOpsgenieNotifier(options), nil
+	},
+	"pagerduty": func(cfg Config, w *watcher.Watcher) (watcher.Notifier, error) {
+		var options PagerDutyConfig
+		if err := cfg.Decode(&options); err != nil {
+			return nil, err
+		}
+		return NewPagerDutyNotifier(options), nil
+	},
+	"pushover": func(cfg Config, w *watcher.Watcher) (watcher.Notifier, error) {
+		var options PushoverConfig
+		if err := cfg.Decode(&options); err != nil {
+			return nil, err
+		}
+		return NewPushoverNotifier(options), nil
+	},
+	"sms": func(cfg Config, w *watcher.Watcher) (watcher.Notifier, error) {
+		var options SMSConfig
+		if err := cfg.Decode(&options); err != nil {
+			return nil, err
+		}
+		return NewSMSNotifier(options), nil
+	},
+	"smtp": func(cfg Config, w *watcher.Watcher) (watcher.Notifier, error) {
+		var options SMTPConfig
+		if err := cfg.Decode(&options); err != nil {
+			return nil, err
+		}
+		return NewSMTPNotifier(options), nil
+	},
+	"syslog": func(cfg Config, w *watcher.Watcher) (watcher.Notifier, error) {
+		var options SyslogConfig
+		if err := cfg.Decode(&options); err != nil {
+			return nil, err
+		}
+		return NewSyslogNotifier(options), nil
+	},
+	"webhook": func(cfg Config, w *watcher.Watcher) (watcher.Notifier, error) {
+		var options WebhookConfig
+		if err := cfg.Decode(&options); err != nil {
+			return nil, err
+		}
+		return NewWebhookNotifier(options), nil
+	},
+}
+
+// New creates notifier instance of configured type,
+// app locale is used unless notifier sets its own,
+// notifiers store messages in watcher outbox,
+// delivery attempts are recorded in watcher delivery log
+func New(cfg Config, w *watcher.Watcher) (watcher.Notifier, error) {
+	create, ok := registry[cfg.Type]
+	if !ok {
+		return nil, errors.New("unknown notifier type " + cfg.Type)
+	}
+	locale := w.Locale()
+	options := map[string]interface{}{
+		"timezone": locale.Location.String(),
+		"language": locale.Language,
+	}
+	for key, value := range cfg.options {
+		options[key] = value
+	}
+	cfg.options = options
+	notifier, err := create(cfg, w)
+	if err != nil {
+		return nil, err
+	}
+	if n, ok := notifier.(interface{ setOutbox(outbox) }); ok {
+		n.setOutbox(w)
+	}
+	if n, ok := notifier.(interface{ setDeliveryLog(deliveryLog) }); ok {
+		n.setDeliveryLog(w)
+	}
+	if n, ok := notifier.(interface{ setURLStates(urlStates) }); ok {
+		n.setURLStates(w)
+	}
+	if n, ok := notifier.(interface{ setAcknowledger(acknowledger) }); ok {
+		n.setAcknowledger(w)
+	}
+	return notifier, nil
+}
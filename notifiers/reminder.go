@@ -0,0 +1,134 @@
+package notifiers
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rbhz/web_watcher/watcher"
+)
+
+// ReminderConfig describes repeated notifications of urls which stay down
+type ReminderConfig struct {
+	// ReminderInterval in seconds, reminders are disabled if it's zero
+	ReminderInterval time.Duration
+	// MaxReminders sent per outage, unlimited if it's zero
+	MaxReminders int
+}
+
+// urlStates provides current state of urls for reminders
+type urlStates interface {
+	GetUrls() []watcher.URL
+	InMaintenance(u watcher.URL) bool
+}
+
+type reminder struct {
+	update watcher.URLUpdate
+	sent   int
+	next   time.Time
+}
+
+// reminders tracks failed urls & repeats notifications while they stay down
+type reminders struct {
+	interval time.Duration
+	max      int
+	states   urlStates
+	active   map[string]*reminder
+	mux      sync.Mutex
+}
+
+func newReminders(cfg ReminderConfig) *reminders {
+	if cfg.ReminderInterval <= 0 {
+		return nil
+	}
+	return &reminders{
+		interval: cfg.ReminderInterval * time.Second,
+		max:      cfg.MaxReminders,
+		active:   make(map[string]*reminder),
+	}
+}
+
+// track starts reminders of failed urls & stops them on recovery,
+// acknowledgements are skipped as reminders of acknowledged urls aren't sent
+func (r *reminders) track(update watcher.URLUpdate) {
+	if r == nil || update.Acked() {
+		return
+	}
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	link := update.New.Link
+	if update.New.Good() {
+		delete(r.active, link)
+		return
+	}
+	rem, ok := r.active[link]
+	if !ok {
+		rem = &reminder{}
+		r.active[link] = rem
+	}
+	rem.update = update
+	rem.next = update.Created.Add(r.interval)
+}
+
+// due returns reminders of urls which are still down after interval,
+// urls recovered during maintenance, acknowledged or unreachable due to parent are skipped
+func (r *reminders) due(now time.Time) []watcher.URLUpdate {
+	if r == nil {
+		return nil
+	}
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	current := make(map[string]watcher.URL)
+	if r.states != nil {
+		for _, url := range r.states.GetUrls() {
+			current[url.Link] = url
+		}
+	}
+	links := make([]string, 0, len(r.active))
+	for link := range r.active {
+		links = append(links, link)
+	}
+	sort.Strings(links)
+	updates := make([]watcher.URLUpdate, 0)
+	for _, link := range links {
+		rem := r.active[link]
+		if now.Before(rem.next) || (r.max != 0 && rem.sent >= r.max) {
+			continue
+		}
+		url := rem.update.New
+		if state, ok := current[link]; ok {
+			url = state
+		}
+		if url.Good() {
+			delete(r.active, link)
+			continue
+		}
+		rem.next = now.Add(r.interval)
+		if url.AckedBy != "" || url.UnreachableVia != "" || (r.states != nil && r.states.InMaintenance(url)) {
+			continue
+		}
+		rem.sent++
+		updates = append(updates, watcher.URLUpdate{
+			New:        url,
+			Old:        url,
+			Created:    now,
+			Dependents: rem.update.Dependents,
+			Reminder:   rem.sent,
+		})
+	}
+	return updates
+}
+
+// restore starts reminders of urls which are down on start & accepted by match
+func (r *reminders) restore(match func(watcher.URLUpdate) bool) {
+	if r == nil || r.states == nil {
+		return
+	}
+	now := time.Now()
+	for _, url := range r.states.GetUrls() {
+		update := watcher.URLUpdate{New: url, Old: url, Created: now}
+		if !url.Good() && match(update) {
+			r.track(update)
+		}
+	}
+}
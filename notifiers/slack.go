@@ -1,11 +1,7 @@
 package notifiers
 
 import (
-	"bytes"
-	"encoding/json"
-	"net/http"
-	"time"
-
+	"github.com/rbhz/web_watcher/watcher"
 	"github.com/rs/zerolog/log"
 )
 
@@ -19,32 +15,14 @@ type SlackNotifier struct {
 	webHookURL string
 }
 
-func (n *SlackNotifier) sendMessage(message string) {
+func (n *SlackNotifier) deliver(m message) error {
 	n.log(log.Info).Msg("sending message")
-	data, err := json.Marshal(&slackSendMessageRequest{
-		Text: message,
-	})
-	if err != nil {
-		n.log(log.Error).Err(err).Msg("Failed to endode data")
-		return
-	}
-	req, err := http.NewRequest(
-		"POST", n.webHookURL, bytes.NewReader(data),
-	)
-	if err != nil {
-		n.log(log.Error).Err(err).Msg("Failed to create request obj")
-		return
-	}
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		n.log(log.Error).Err(err).Msg("Failed to perform request")
-		return
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		n.log(log.Error).Int("code", resp.StatusCode).Msg("Slack returned invalid code")
-	}
+	return n.postJSON(n.webHookURL, &slackSendMessageRequest{Text: m.Text}, nil)
+}
+
+// NotifyDigest sends digest report
+func (n *SlackNotifier) NotifyDigest(d watcher.Digest) {
+	n.sendDigest(d)
 }
 
 // NewSlackNotifier Creates new slack notifier
@@ -52,8 +30,11 @@ func NewSlackNotifier(cfg SlackConfig) *SlackNotifier {
 	notifier := SlackNotifier{
 		webHookURL: cfg.WebHookURL,
 		baseMessageNotifier: baseMessageNotifier{
-			name:          "slack",
-			messagePeriod: cfg.MessagePeriod}}
-	notifier.sendFunc = notifier.sendMessage
+			baseNotifier:  newBaseNotifier(cfg.Name, cfg.Routes),
+			messagePeriod: cfg.MessagePeriod,
+			reminders:     newReminders(cfg.ReminderConfig),
+			templates:     newMessageTemplates(cfg.Name, cfg.MessageTemplate)}}
+	notifier.deliverFunc = notifier.deliver
+	notifier.sendFunc = notifier.sendRendered
 	return &notifier
 }
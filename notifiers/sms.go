@@ -0,0 +1,124 @@
+package notifiers
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rbhz/web_watcher/watcher"
+	"github.com/rs/zerolog/log"
+)
+
+const smsSegmentLength = 160
+
+// smsDefaultTemplate is short version of default text template without links
+const smsDefaultTemplate = `
+{{- range $idx, $update := .Updates }}{{ if $idx }}
+{{ end }}{{ .URL.Name }}: {{ if .Good }}{{ t "ok" }}
+{{- with .Downtime }} ({{ t "recovered_after" (duration .) }}){{ end }}
+{{- else }}{{ .Error }}{{ if .AckedBy }} ({{ t "acked_by" .AckedBy }})
+{{- else if .Reminder }} ({{ t "still_down" (duration .Downtime) }}){{ end }}{{ end }}
+{{- end }}`
+
+// SMSNotifier sends sms about critical urls via Twilio messages API
+type SMSNotifier struct {
+	apiURL      string
+	accountSID  string
+	authToken   string
+	from        string
+	maxMessages int
+	baseMessageNotifier
+}
+
+// smsSegments packs lines of text into segments of smsSegmentLength,
+// lines which don't fit in limit are replaced with counter
+func (n *SMSNotifier) smsSegments(text string) []string {
+	var (
+		segments []string
+		counts   []int
+		current  string
+		count    int
+	)
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		line = truncate(line, smsSegmentLength)
+		if current != "" && utf8.RuneCountInString(current)+1+utf8.RuneCountInString(line) > smsSegmentLength {
+			segments, counts = append(segments, current), append(counts, count)
+			current, count = "", 0
+		}
+		if current != "" {
+			current += "\n"
+		}
+		current += line
+		count++
+	}
+	if current != "" {
+		segments, counts = append(segments, current), append(counts, count)
+	}
+	if len(segments) > n.maxMessages {
+		skipped := 0
+		for _, count := range counts[n.maxMessages-1:] {
+			skipped += count
+		}
+		segments = append(segments[:n.maxMessages-1], n.templates.locale.T("more", skipped))
+	}
+	return segments
+}
+
+// deliver sends message segments to number from message target
+func (n *SMSNotifier) deliver(m message) error {
+	n.log(log.Info).Str("to", m.Target).Msg("sending message")
+	endpoint := n.apiURL + "/2010-04-01/Accounts/" + url.PathEscape(n.accountSID) + "/Messages.json"
+	auth := base64.StdEncoding.EncodeToString([]byte(n.accountSID + ":" + n.authToken))
+	headers := map[string]string{"Authorization": "Basic " + auth}
+	for _, segment := range n.smsSegments(m.Text) {
+		form := url.Values{"From": {n.from}, "To": {m.Target}, "Body": {segment}}
+		if err := n.postForm(endpoint, form, headers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NotifyDigest sends digest report to each recipient
+func (n *SMSNotifier) NotifyDigest(d watcher.Digest) {
+	n.sendDigest(d)
+}
+
+// NewSMSNotifier creates notifier
+func NewSMSNotifier(cfg SMSConfig) *SMSNotifier {
+	if cfg.AccountSID == "" || cfg.AuthToken == "" {
+		log.Fatal().Str("notifier", cfg.Name).Msg("Specify Twilio account sid & auth token")
+	}
+	if cfg.From == "" || len(cfg.To) == 0 {
+		log.Fatal().Str("notifier", cfg.Name).Msg("Specify sms sender & recipients")
+	}
+	if cfg.MaxMessages < 1 {
+		log.Fatal().Str("notifier", cfg.Name).Msg("MaxMessages should be positive")
+	}
+	if cfg.Template == "" {
+		cfg.Template = smsDefaultTemplate
+	}
+	notifier := SMSNotifier{
+		apiURL:      strings.TrimRight(cfg.APIURL, "/"),
+		accountSID:  cfg.AccountSID,
+		authToken:   cfg.AuthToken,
+		from:        cfg.From,
+		maxMessages: cfg.MaxMessages,
+		baseMessageNotifier: baseMessageNotifier{
+			baseNotifier:  newBaseNotifier(cfg.Name, cfg.Routes),
+			messagePeriod: cfg.MessagePeriod,
+			templates:     newMessageTemplates(cfg.Name, cfg.MessageTemplate),
+			reminders:     newReminders(cfg.ReminderConfig)}}
+	notifier.targets = cfg.To
+	// sms are sent about critical urls only
+	notifier.filter = func(update watcher.URLUpdate) bool {
+		return update.New.Severity == watcher.SeverityCritical
+	}
+	notifier.deliverFunc = notifier.deliver
+	notifier.sendFunc = notifier.sendRendered
+	return &notifier
+}
@@ -0,0 +1,212 @@
+package notifiers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rbhz/web_watcher/watcher"
+	"github.com/rs/zerolog/log"
+)
+
+// SMTP TLS modes
+const (
+	smtpTLSNone     = "none"
+	smtpTLSStartTLS = "starttls"
+	smtpTLSImplicit = "tls"
+)
+
+// SMTPNotifier sends notifications via SMTP server
+type SMTPNotifier struct {
+	host     string
+	port     int
+	tlsMode  string
+	username string
+	password string
+	from     string
+	to       []string
+	cc       []string
+	subject  string
+	baseMessageNotifier
+}
+
+func (n *SMTPNotifier) deliver(m message) error {
+	n.log(log.Info).Msg("sending message")
+	message, err := n.buildMessage(m.title(n.subject), m.Text, m.HTML)
+	if err != nil {
+		return err
+	}
+	started := time.Now()
+	err = n.send(message)
+	n.recordDelivery("smtp://"+net.JoinHostPort(n.host, strconv.Itoa(n.port)), message, 0, started, err)
+	return err
+}
+
+// NotifyDigest sends digest report
+func (n *SMTPNotifier) NotifyDigest(d watcher.Digest) {
+	n.sendDigest(d)
+}
+
+func (n *SMTPNotifier) buildMessage(subject, text, html string) ([]byte, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", part.contentType)
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+		partWriter, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(partWriter)
+		if _, err := qp.Write([]byte(strings.Replace(part.content, "\n", "\r\n", -1))); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	var message bytes.Buffer
+	headers := [][2]string{
+		{"From", n.from},
+		{"To", strings.Join(n.to, ", ")},
+		{"Cc", strings.Join(n.cc, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", n.messageID()},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + writer.Boundary()},
+	}
+	for _, header := range headers {
+		if header[1] == "" {
+			continue
+		}
+		fmt.Fprintf(&message, "%s: %s\r\n", header[0], header[1])
+	}
+	message.WriteString("\r\n")
+	message.Write(body.Bytes())
+	return message.Bytes(), nil
+}
+
+func (n *SMTPNotifier) messageID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return "<" + hex.EncodeToString(id) + "@" + n.host + ">"
+}
+
+func (n *SMTPNotifier) dial() (*smtp.Client, error) {
+	addr := net.JoinHostPort(n.host, strconv.Itoa(n.port))
+	tlsConfig := &tls.Config{ServerName: n.host}
+	var (
+		conn net.Conn
+		err  error
+	)
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if n.tlsMode == smtpTLSImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+	client, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if n.tlsMode == smtpTLSStartTLS {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+func (n *SMTPNotifier) send(message []byte) error {
+	client, err := n.dial()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if n.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.username, n.password, n.host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(n.from); err != nil {
+		return err
+	}
+	for _, rcpt := range append(append([]string{}, n.to...), n.cc...) {
+		if err := client.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(message); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// NewSMTPNotifier creates notifier
+func NewSMTPNotifier(cfg SMTPConfig) *SMTPNotifier {
+	if cfg.Host == "" {
+		log.Fatal().Str("notifier", cfg.Name).Msg("Specify SMTP host")
+	}
+	if cfg.From == "" {
+		log.Fatal().Str("notifier", cfg.Name).Msg("Specify SMTP from address")
+	}
+	if len(cfg.To) == 0 {
+		log.Fatal().Str("notifier", cfg.Name).Msg("Specify SMTP recipients")
+	}
+	switch cfg.TLS {
+	case smtpTLSNone, smtpTLSStartTLS, smtpTLSImplicit:
+	default:
+		log.Fatal().Str("notifier", cfg.Name).Str("tls", cfg.TLS).Msg("Invalid SMTP TLS mode")
+	}
+	notifier := SMTPNotifier{
+		host:     cfg.Host,
+		port:     cfg.Port,
+		tlsMode:  cfg.TLS,
+		username: cfg.Username,
+		password: cfg.Password,
+		from:     cfg.From,
+		to:       cfg.To,
+		cc:       cfg.CC,
+		subject:  cfg.Subject,
+		baseMessageNotifier: baseMessageNotifier{
+			baseNotifier:  newBaseNotifier(cfg.Name, cfg.Routes),
+			messagePeriod: cfg.MessagePeriod,
+			reminders:     newReminders(cfg.ReminderConfig),
+			templates:     newMessageTemplates(cfg.Name, cfg.MessageTemplate)}}
+	notifier.deliverFunc = notifier.deliver
+	notifier.sendFunc = notifier.sendRendered
+	return &notifier
+}
@@ -0,0 +1,204 @@
+package notifiers
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rbhz/web_watcher/watcher"
+	"github.com/rs/zerolog/log"
+)
+
+// syslogSDID is structured data id of update parameters
+const syslogSDID = "update@32473"
+
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// syslogSeverities maps severities of failed urls to syslog severities,
+// recoveries are logged as notice
+var syslogSeverities = map[string]int{
+	watcher.SeverityCritical: 2,
+	watcher.SeverityError:    3,
+	watcher.SeverityWarning:  4,
+	watcher.SeverityInfo:     6,
+}
+
+const syslogNotice = 5
+
+// local syslog sockets
+var syslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+var sdEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// SyslogNotifier writes updates to syslog in RFC 5424 format
+type SyslogNotifier struct {
+	network  string
+	address  string
+	facility int
+	hostname string
+	appName  string
+	conn     net.Conn
+	mux      sync.Mutex
+	baseNotifier
+}
+
+// Notify writes update to syslog
+func (n *SyslogNotifier) Notify(update watcher.URLUpdate) {
+	n.dispatch([]watcher.URLUpdate{update})
+}
+
+// sendMessages writes updates from outbox message to syslog
+func (n *SyslogNotifier) sendMessages(m watcher.OutboxMessage) error {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+	for _, update := range m.Updates {
+		message := n.format(update)
+		started := time.Now()
+		err := n.write(message)
+		if err != nil {
+			// connection could be closed by server, reconnect once
+			n.close()
+			err = n.write(message)
+		}
+		if err != nil {
+			n.close()
+		}
+		n.recordDelivery(n.target(), []byte(message), 0, started, err)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// target returns syslog address for delivery log
+func (n *SyslogNotifier) target() string {
+	if n.network == "" {
+		return "unix://" + n.address
+	}
+	return n.network + "://" + n.address
+}
+
+func (n *SyslogNotifier) format(update watcher.URLUpdate) string {
+	e := newEvent(update)
+	severity := syslogNotice
+	msg := e.Name + ": OK"
+	if errText := update.Error(); errText != nil {
+		severity = syslogSeverities[e.Severity]
+		msg = e.Name + ": " + *errText
+	}
+	msgID := "-"
+	if len(e.Changes) != 0 {
+		msgID = strings.Join(e.Changes, ",")
+	}
+	params := [][2]string{
+		{"url", e.URL},
+		{"name", e.Name},
+		{"group", e.Group},
+		{"tags", strings.Join(e.Tags, ",")},
+		{"severity", e.Severity},
+		{"changes", strings.Join(e.Changes, ",")},
+		{"status", strconv.Itoa(e.Status)},
+		{"error", e.Error},
+		{"old_status", strconv.Itoa(e.OldStatus)},
+		{"response_time", strconv.FormatFloat(e.ResponseTime, 'f', 3, 64)},
+		{"unreachable_via", e.Unreachable},
+	}
+	var sd strings.Builder
+	sd.WriteString("[" + syslogSDID)
+	for _, param := range params {
+		if param[1] != "" {
+			fmt.Fprintf(&sd, ` %s="%s"`, param[0], sdEscaper.Replace(param[1]))
+		}
+	}
+	sd.WriteString("]")
+	return fmt.Sprintf("<%d>1 %s %s %s %d %s %s %s",
+		n.facility*8+severity, e.Time.Format(time.RFC3339Nano), n.hostname, n.appName,
+		os.Getpid(), msgID, sd.String(), msg)
+}
+
+func (n *SyslogNotifier) dial() (net.Conn, error) {
+	if n.network != "" {
+		return net.DialTimeout(n.network, n.address, 5*time.Second)
+	}
+	sockets := syslogSockets
+	if n.address != "" {
+		sockets = []string{n.address}
+	}
+	for _, socket := range sockets {
+		for _, network := range []string{"unixgram", "unix"} {
+			if conn, err := net.Dial(network, socket); err == nil {
+				return conn, nil
+			}
+		}
+	}
+	return nil, errors.New("local syslog socket not found")
+}
+
+func (n *SyslogNotifier) write(message string) error {
+	if n.conn == nil {
+		conn, err := n.dial()
+		if err != nil {
+			return err
+		}
+		n.conn = conn
+	}
+	if n.network == "tcp" {
+		// octet counting framing, RFC 6587
+		message = strconv.Itoa(len(message)) + " " + message
+	}
+	n.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	_, err := n.conn.Write([]byte(message))
+	return err
+}
+
+func (n *SyslogNotifier) close() {
+	if n.conn != nil {
+		n.conn.Close()
+		n.conn = nil
+	}
+}
+
+// NewSyslogNotifier creates notifier
+func NewSyslogNotifier(cfg SyslogConfig) *SyslogNotifier {
+	switch cfg.Network {
+	case "":
+	case "udp", "tcp":
+		if cfg.Address == "" {
+			log.Fatal().Str("notifier", cfg.Name).Msg("Specify syslog address")
+		}
+	default:
+		log.Fatal().Str("notifier", cfg.Name).Str("network", cfg.Network).Msg("Invalid syslog network")
+	}
+	facility, ok := syslogFacilities[cfg.Facility]
+	if !ok {
+		log.Fatal().Str("notifier", cfg.Name).Str("facility", cfg.Facility).Msg("Invalid syslog facility")
+	}
+	hostname := cfg.Hostname
+	if hostname == "" {
+		hostname, _ = os.Hostname()
+		if hostname == "" {
+			hostname = "-"
+		}
+	}
+	notifier := &SyslogNotifier{
+		network:      cfg.Network,
+		address:      cfg.Address,
+		facility:     facility,
+		hostname:     hostname,
+		appName:      cfg.AppName,
+		baseNotifier: newBaseNotifier(cfg.Name, cfg.Routes),
+	}
+	notifier.sendFunc = notifier.sendMessages
+	return notifier
+}
@@ -0,0 +1,187 @@
+package notifiers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/rbhz/web_watcher/watcher"
+	"github.com/rs/zerolog/log"
+)
+
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string    `json:"contentType"`
+	Content     teamsCard `json:"content"`
+}
+
+type teamsCard struct {
+	Schema  string        `json:"$schema"`
+	Type    string        `json:"type"`
+	Version string        `json:"version"`
+	Body    []interface{} `json:"body"`
+}
+
+type teamsTextBlock struct {
+	Type   string `json:"type"`
+	Text   string `json:"text"`
+	Weight string `json:"weight,omitempty"`
+	Size   string `json:"size,omitempty"`
+	Color  string `json:"color,omitempty"`
+	Wrap   bool   `json:"wrap"`
+}
+
+type teamsFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+type teamsFactSet struct {
+	Type  string      `json:"type"`
+	Facts []teamsFact `json:"facts"`
+}
+
+type teamsAction struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+type teamsActionSet struct {
+	Type    string        `json:"type"`
+	Actions []teamsAction `json:"actions"`
+}
+
+type teamsContainer struct {
+	Type      string        `json:"type"`
+	Style     string        `json:"style"`
+	Separator bool          `json:"separator"`
+	Items     []interface{} `json:"items"`
+}
+
+// TeamsNotifier sends adaptive cards to microsoft teams incoming webhook
+type TeamsNotifier struct {
+	webHookURL   string
+	title        string
+	dashboardURL string
+	baseMessageNotifier
+}
+
+func (n *TeamsNotifier) section(update watcher.URLUpdate) teamsContainer {
+	url := update.New
+	locale := n.templates.locale
+	style, color, status := "good", "Good", locale.T("ok")
+	if errText := update.LocalizedError(locale); errText != nil {
+		style, color, status = "attention", "Attention", *errText
+		if url.UnreachableVia != "" {
+			style, color = "warning", "Warning"
+		}
+	}
+	facts := []teamsFact{
+		{Title: locale.T("url"), Value: url.Link},
+		{Title: locale.T("status_column"), Value: status},
+		{Title: locale.T("status_code"), Value: strconv.Itoa(url.Status)},
+		{Title: locale.T("time"), Value: locale.Time(update.Created)},
+	}
+	if count := len(update.Dependents); count != 0 {
+		facts = append(facts, teamsFact{Title: locale.T("dependents_column"), Value: strconv.Itoa(count)})
+	}
+	container := teamsContainer{
+		Type:      "Container",
+		Style:     style,
+		Separator: true,
+		Items: []interface{}{
+			teamsTextBlock{Type: "TextBlock", Text: url.Name, Weight: "Bolder", Color: color, Wrap: true},
+		},
+	}
+	if text := downtimeText(update, locale); text != "" {
+		container.Items = append(container.Items, teamsTextBlock{Type: "TextBlock", Text: text, Wrap: true})
+	}
+	container.Items = append(container.Items, teamsFactSet{Type: "FactSet", Facts: facts})
+	if n.dashboardURL != "" {
+		container.Items = append(container.Items, teamsActionSet{
+			Type: "ActionSet",
+			Actions: []teamsAction{{
+				Type:  "Action.OpenUrl",
+				Title: locale.T("dashboard"),
+				URL:   monitorLink(n.dashboardURL, url),
+			}},
+		})
+	}
+	return container
+}
+
+func (n *TeamsNotifier) sendMessage(m watcher.OutboxMessage) error {
+	n.log(log.Info).Msg("sending message")
+	body := []interface{}{
+		teamsTextBlock{Type: "TextBlock", Text: n.title, Weight: "Bolder", Size: "Medium", Wrap: true},
+	}
+	for _, update := range m.Updates {
+		body = append(body, n.section(update))
+	}
+	return n.postJSON(n.webHookURL, teamsCardMessage(body), nil)
+}
+
+// NotifyDigest sends digest report as card with text of digest template
+func (n *TeamsNotifier) NotifyDigest(d watcher.Digest) {
+	n.log(log.Info).Str("digest", d.Name).Msg("Sending digest")
+	rendered := n.templates.digest(d)
+	body := []interface{}{
+		teamsTextBlock{Type: "TextBlock", Text: rendered.Title, Weight: "Bolder", Size: "Medium", Wrap: true},
+	}
+	// text blocks don't keep single line breaks
+	for _, line := range strings.Split(rendered.Text, "\n") {
+		body = append(body, teamsTextBlock{Type: "TextBlock", Text: line, Wrap: true})
+	}
+	if n.dashboardURL != "" {
+		body = append(body, teamsActionSet{
+			Type: "ActionSet",
+			Actions: []teamsAction{{
+				Type:  "Action.OpenUrl",
+				Title: n.templates.locale.T("dashboard"),
+				URL:   n.dashboardURL,
+			}},
+		})
+	}
+	if err := n.postJSON(n.webHookURL, teamsCardMessage(body), nil); err != nil {
+		n.log(log.Error).Err(err).Str("digest", d.Name).Msg("Failed to send digest")
+	}
+}
+
+// teamsCardMessage wraps card body into webhook message
+func teamsCardMessage(body []interface{}) *teamsMessage {
+	return &teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content: teamsCard{
+				Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+				Type:    "AdaptiveCard",
+				Version: "1.4",
+				Body:    body,
+			},
+		}},
+	}
+}
+
+// NewTeamsNotifier creates notifier
+func NewTeamsNotifier(cfg TeamsConfig) *TeamsNotifier {
+	if cfg.WebHookURL == "" {
+		log.Fatal().Str("notifier", cfg.Name).Msg("Specify Teams webhook url")
+	}
+	notifier := TeamsNotifier{
+		webHookURL:   cfg.WebHookURL,
+		title:        cfg.Title,
+		dashboardURL: cfg.DashboardURL,
+		baseMessageNotifier: baseMessageNotifier{
+			baseNotifier:  newBaseNotifier(cfg.Name, cfg.Routes),
+			messagePeriod: cfg.MessagePeriod,
+			templates: newMessageTemplates(cfg.Name, MessageTemplate{
+				DashboardURL: cfg.DashboardURL, Timezone: cfg.Timezone, Language: cfg.Language}),
+			reminders: newReminders(cfg.ReminderConfig)}}
+	notifier.sendFunc = notifier.sendMessage
+	return &notifier
+}
@@ -1,34 +1,99 @@
 package notifiers
 
 import (
-	"sync"
+	"strconv"
+	"strings"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/rbhz/web_watcher/watcher"
 	"github.com/rs/zerolog/log"
 )
 
 // TelegramNotifier Sends notifications via TelegramBotAPI
 type TelegramNotifier struct {
-	bot   *tgbotapi.BotAPI
-	users []int64
+	bot      *tgbotapi.BotAPI
+	users    []int64
+	commands bool
 	baseMessageNotifier
 }
 
-func (n *TelegramNotifier) sendMessage(message string) {
-	n.log(log.Info).Msg("sending messages")
-	wg := sync.WaitGroup{}
-	for _, user := range n.users {
-		wg.Add(1)
-		go func(user int64) {
-			defer wg.Done()
-			msg := tgbotapi.NewMessage(user, message)
-			_, err := n.bot.Send(msg)
-			if err != nil {
-				n.log(log.Error).Err(err).Msg("Failed to send telegram message")
-			}
-		}(user)
-	}
-	wg.Wait()
+// Run sends messages & handles commands if they are enabled
+func (n *TelegramNotifier) Run() {
+	if n.commands && n.acks != nil {
+		go n.handleCommands()
+	}
+	n.baseMessageNotifier.Run()
+}
+
+// handleCommands acknowledges incidents by /ack command from users chats
+func (n *TelegramNotifier) handleCommands() {
+	config := tgbotapi.NewUpdate(0)
+	config.Timeout = 60
+	updates, err := n.bot.GetUpdatesChan(config)
+	if err != nil {
+		n.log(log.Error).Err(err).Msg("Failed to get telegram updates")
+		return
+	}
+	for update := range updates {
+		msg := update.Message
+		if msg == nil || !msg.IsCommand() || msg.Command() != "ack" {
+			continue
+		}
+		allowed := false
+		for _, user := range n.users {
+			allowed = allowed || user == msg.Chat.ID
+		}
+		if !allowed {
+			n.log(log.Warn).Int64("chat", msg.Chat.ID).Msg("Command from unknown chat")
+			continue
+		}
+		reply := n.ack(strings.TrimSpace(msg.CommandArguments()), msg.From)
+		if _, err := n.bot.Send(tgbotapi.NewMessage(msg.Chat.ID, reply)); err != nil {
+			n.log(log.Error).Err(err).Int64("chat", msg.Chat.ID).Msg("Failed to reply to command")
+		}
+	}
+}
+
+// ack acknowledges incident & returns reply message
+func (n *TelegramNotifier) ack(link string, from *tgbotapi.User) string {
+	locale := n.templates.locale
+	if link == "" {
+		return locale.T("ack_usage")
+	}
+	by := "telegram"
+	if from != nil {
+		by += ":" + from.String()
+	}
+	url, err := n.acks.Acknowledge(link, by)
+	switch err {
+	case nil:
+		return locale.T("ack_done", url.Name, url.AckedBy)
+	case watcher.ErrUnknownURL:
+		return locale.T("ack_unknown", link)
+	case watcher.ErrNoIncident:
+		return locale.T("ack_no_incident", url.Name)
+	}
+	n.log(log.Error).Err(err).Str("url", link).Msg("Failed to acknowledge incident")
+	return err.Error()
+}
+
+// deliver sends message to user chat from message target
+func (n *TelegramNotifier) deliver(m message) error {
+	user, err := strconv.ParseInt(m.Target, 10, 64)
+	if err != nil {
+		return err
+	}
+	n.log(log.Info).Int64("user", user).Msg("sending message")
+	started := time.Now()
+	_, err = n.bot.Send(tgbotapi.NewMessage(user, m.Text))
+	n.recordDelivery("telegram:"+m.Target, []byte(m.Text), 0, started, err)
+	return err
+}
+
+// NotifyDigest sends digest report
+func (n *TelegramNotifier) NotifyDigest(d watcher.Digest) {
+	n.sendDigest(d)
 }
 
 // NewTelegramNotifier creates notifier
@@ -38,11 +103,18 @@ func NewTelegramNotifier(cfg TelegramConfig) *TelegramNotifier {
 		log.Fatal().Err(err).Msg("Failed to initialize Telegram bot")
 	}
 	notifier := TelegramNotifier{
-		bot:   bot,
-		users: cfg.Users,
+		bot:      bot,
+		users:    cfg.Users,
+		commands: cfg.Commands,
 		baseMessageNotifier: baseMessageNotifier{
+			baseNotifier:  newBaseNotifier(cfg.Name, cfg.Routes),
 			messagePeriod: cfg.MessagePeriod,
-			name:          "telegram"}}
-	notifier.sendFunc = notifier.sendMessage
+			reminders:     newReminders(cfg.ReminderConfig),
+			templates:     newMessageTemplates(cfg.Name, cfg.MessageTemplate)}}
+	for _, user := range cfg.Users {
+		notifier.targets = append(notifier.targets, strconv.FormatInt(user, 10))
+	}
+	notifier.deliverFunc = notifier.deliver
+	notifier.sendFunc = notifier.sendRendered
 	return &notifier
 }
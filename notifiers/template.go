@@ -0,0 +1,319 @@
+package notifiers
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/rbhz/web_watcher/watcher"
+	"github.com/rs/zerolog/log"
+)
+
+const defaultTextTemplate = `
+{{- range $idx, $update := .Updates }}{{ if $idx }}
+{{ end }}{{ formatTime .Created }} {{ .URL.Link }}: {{ if .Good }}{{ t "ok" }}
+{{- with .Downtime }} ({{ t "recovered_after" (duration .) }}){{ end }}
+{{- else }}{{ .Error }}{{ if .AckedBy }} ({{ t "acked_by" .AckedBy }})
+{{- else if .Reminder }} ({{ t "still_down" (duration .Downtime) }})
+{{- else if or .Escalation (not .Old.Good) }} ({{ t "down_for" (duration .Downtime) }}){{ end }}{{ end }}
+{{- with .Dependents }} ({{ t "dependents" (len .) }}){{ end }}
+{{- with .AckLink }} {{ t "ack" }}: {{ . }}{{ end }}
+{{- end }}`
+
+const defaultHTMLTemplate = `<table>
+{{- range .Updates }}
+<tr>
+<td>{{ formatTime .Created }}</td>
+<td><a href="{{ .URL.Link }}">{{ .URL.Name }}</a></td>
+<td>{{ if .Good }}<span style="color: green">{{ t "ok" }}</span>
+{{- with .Downtime }} ({{ t "recovered_after" (duration .) }}){{ end }}
+{{- else }}<span style="color: red">{{ .Error }}</span>{{ if .AckedBy }} ({{ t "acked_by" .AckedBy }})
+{{- else if .Reminder }} ({{ t "still_down" (duration .Downtime) }})
+{{- else if or .Escalation (not .Old.Good) }} ({{ t "down_for" (duration .Downtime) }}){{ end }}{{ end }}
+{{- with .Dependents }} ({{ t "dependents" (len .) }}){{ end }}</td>
+{{- with .DashboardLink }}
+<td><a href="{{ . }}">{{ t "dashboard" }}</a></td>
+{{- end }}
+{{- with .AckLink }}
+<td><a href="{{ . }}">{{ t "ack" }}</a></td>
+{{- end }}
+</tr>
+{{- end }}
+</table>`
+
+const defaultDigestTemplate = `{{ t "digest" .Name }}: {{ formatTime .From }} - {{ formatTime .To }}
+{{ if .NoData }}{{ t "no_data" }}{{ else }}{{ t "uptime" (percent .Uptime) }}{{ end }}, {{ t "incidents" .Incidents }}, {{ t "downtime" (duration .Downtime) }}, {{ t "content_changes" .ContentChanges }}
+{{- range .Monitors }}{{ if or .Incidents .ContentChanges .NoData }}
+{{ .URL.Name }}: {{ if .NoData }}{{ t "no_data" }}{{ else }}{{ t "uptime" (percent .Uptime) }}{{ end }}, {{ t "incidents" .Incidents }}, {{ t "downtime" (duration .Downtime) }}, {{ t "content_changes" .ContentChanges }}
+{{- end }}{{ end }}
+{{- with .Slowest }}
+{{ t "slowest" }}: {{ range $idx, $s := . }}{{ if $idx }}, {{ end }}{{ $s.URL.Name }} {{ responseTime $s.AvgResponse }}{{ end }}
+{{- end }}`
+
+const defaultDigestHTMLTemplate = `<h3>{{ t "digest" .Name }}</h3>
+<p>{{ formatTime .From }} - {{ formatTime .To }}<br>
+{{ if .NoData }}{{ t "no_data" }}{{ else }}{{ t "uptime" (percent .Uptime) }}{{ end }}, {{ t "incidents" .Incidents }}, {{ t "downtime" (duration .Downtime) }}, {{ t "content_changes" .ContentChanges }}</p>
+<table>
+<tr><th>{{ t "url" }}</th><th>{{ t "uptime_column" }}</th><th>{{ t "incident_column" }}</th><th>{{ t "downtime_column" }}</th><th>{{ t "changes_column" }}</th><th>{{ t "avg_response" }}</th></tr>
+{{- range .Monitors }}
+<tr>
+<td><a href="{{ .URL.Link }}">{{ .URL.Name }}</a></td>
+<td{{ if .Incidents }} style="color: red"{{ end }}>{{ if .NoData }}{{ t "no_data" }}{{ else }}{{ percent .Uptime }}{{ end }}</td>
+<td>{{ .Incidents }}</td>
+<td>{{ duration .Downtime }}</td>
+<td>{{ .ContentChanges }}</td>
+<td>{{ responseTime .AvgResponse }}</td>
+</tr>
+{{- end }}
+</table>
+{{- with .Slowest }}
+<h4>{{ t "slowest" }}</h4>
+<ol>
+{{- range . }}
+<li>{{ .URL.Name }}: {{ responseTime .AvgResponse }}</li>
+{{- end }}
+</ol>
+{{- end }}
+{{- with .DashboardURL }}
+<p><a href="{{ . }}">{{ t "dashboard" }}</a></p>
+{{- end }}`
+
+// message is notification rendered by templates
+type message struct {
+	// Title overrides configured title or subject if it's set
+	Title string
+	Text  string
+	HTML  string
+	// Severity is highest severity of failed urls, info for recoveries & digests
+	Severity string
+	Failed   bool
+	// Target is recipient of notifiers tracking delivery per recipient
+	Target string
+	// Key identifies outbox message & stays the same for its retries,
+	// it's empty for messages sent without outbox
+	Key string
+}
+
+// title returns message title or default one
+func (m message) title(fallback string) string {
+	if m.Title != "" {
+		return m.Title
+	}
+	return fallback
+}
+
+// messageUpdate describes single update passed to message templates
+type messageUpdate struct {
+	// URL & Old are url states after & before update
+	URL     watcher.URL
+	Old     watcher.URL
+	Created time.Time
+	// Changes contains names of changes: status, content, error, ack
+	Changes []string
+	Good    bool
+	// Error is empty for good urls
+	Error string
+	// Duration is time passed since previous change of url
+	Duration time.Duration
+	// Downtime is time url is down or was down before recovery
+	Downtime time.Duration
+	// Reminder is number of reminder of url which is still down, zero for changes
+	Reminder int
+	// Escalation is number of escalation step, zero for changes
+	Escalation int
+	// AckedBy is set for updates acknowledging incident
+	AckedBy    string
+	Dependents []string
+	// DashboardLink is empty unless dashboard url is configured
+	DashboardLink string
+	// AckLink is signed link acknowledging incident of failed url,
+	// empty unless dashboard url & ack secret are configured
+	AckLink string
+}
+
+// messageData is passed to message templates
+type messageData struct {
+	Notifier     string
+	Created      time.Time
+	Updates      []messageUpdate
+	Failed       int
+	Recovered    int
+	DashboardURL string
+}
+
+// digestData is passed to digest templates
+type digestData struct {
+	watcher.Digest
+	Notifier     string
+	DashboardURL string
+}
+
+// funcs returns functions available in templates
+func (t *messageTemplates) funcs() map[string]interface{} {
+	return map[string]interface{}{
+		"t":          t.locale.T,
+		"formatTime": t.locale.Time,
+		"duration":   t.locale.Duration,
+		"join":       strings.Join,
+		"percent": func(value float64) string {
+			return strconv.FormatFloat(value, 'f', 2, 64) + "%"
+		},
+		"responseTime": func(d time.Duration) string {
+			return d.Round(time.Millisecond).String()
+		},
+		// truncate takes text last to be used in pipelines
+		"truncate": func(limit int, text string) string {
+			return truncate(text, limit)
+		},
+	}
+}
+
+// messageTemplates renders notification messages
+type messageTemplates struct {
+	name         string
+	dashboardURL string
+	locale       watcher.Locale
+	acks         acknowledger
+	text         *texttemplate.Template
+	html         *htmltemplate.Template
+	digestText   *texttemplate.Template
+	digestHTML   *htmltemplate.Template
+}
+
+func newMessageTemplates(name string, cfg MessageTemplate) *messageTemplates {
+	locale, err := watcher.NewLocale(cfg.Language, cfg.Timezone)
+	if err != nil {
+		log.Fatal().Err(err).Str("notifier", name).Msg("Invalid locale settings")
+	}
+	templates := &messageTemplates{name: name, dashboardURL: cfg.DashboardURL, locale: locale}
+	text, html := cfg.Template, cfg.HTMLTemplate
+	if text == "" {
+		text = defaultTextTemplate
+	}
+	if html == "" {
+		html = defaultHTMLTemplate
+	}
+	digestText, digestHTML := cfg.DigestTemplate, cfg.DigestHTMLTemplate
+	if digestText == "" {
+		digestText = defaultDigestTemplate
+	}
+	if digestHTML == "" {
+		digestHTML = defaultDigestHTMLTemplate
+	}
+	if templates.text, err = texttemplate.New("text").Funcs(templates.funcs()).Parse(text); err != nil {
+		log.Fatal().Err(err).Str("notifier", name).Msg("Invalid message template")
+	}
+	if templates.html, err = htmltemplate.New("html").Funcs(templates.funcs()).Parse(html); err != nil {
+		log.Fatal().Err(err).Str("notifier", name).Msg("Invalid html message template")
+	}
+	if templates.digestText, err = texttemplate.New("digest").Funcs(templates.funcs()).Parse(digestText); err != nil {
+		log.Fatal().Err(err).Str("notifier", name).Msg("Invalid digest template")
+	}
+	if templates.digestHTML, err = htmltemplate.New("digestHTML").Funcs(templates.funcs()).Parse(digestHTML); err != nil {
+		log.Fatal().Err(err).Str("notifier", name).Msg("Invalid html digest template")
+	}
+	return templates
+}
+
+func (t *messageTemplates) data(updates []watcher.URLUpdate) messageData {
+	data := messageData{
+		Notifier:     t.name,
+		Created:      time.Now(),
+		Updates:      make([]messageUpdate, 0, len(updates)),
+		DashboardURL: t.dashboardURL,
+	}
+	for _, update := range updates {
+		item := messageUpdate{
+			URL:        update.New,
+			Old:        update.Old,
+			Created:    update.Created,
+			Changes:    update.ChangeNames(),
+			Good:       true,
+			Reminder:   update.Reminder,
+			Escalation: update.Escalation,
+			Dependents: update.Dependents,
+		}
+		if !update.Old.LastChange.IsZero() {
+			item.Duration = update.Created.Sub(update.Old.LastChange)
+		}
+		item.Downtime = downtime(update)
+		if errText := update.LocalizedError(t.locale); errText != nil {
+			item.Good, item.Error = false, *errText
+			data.Failed++
+		} else if !update.Old.Good() {
+			data.Recovered++
+		}
+		if t.dashboardURL != "" {
+			item.DashboardLink = monitorLink(t.dashboardURL, update.New)
+		}
+		if update.Acked() {
+			item.AckedBy = update.New.AckedBy
+		}
+		if t.dashboardURL != "" && t.acks != nil && !item.Good && update.New.AckedBy == "" {
+			if token := t.acks.AckToken(update.New); token != "" {
+				item.AckLink = ackLink(t.dashboardURL, update.New, token)
+			}
+		}
+		data.Updates = append(data.Updates, item)
+	}
+	return data
+}
+
+// Text renders plain text message, default template is used on errors
+func (t *messageTemplates) Text(updates []watcher.URLUpdate) string {
+	var message bytes.Buffer
+	data := t.data(updates)
+	if err := t.text.Execute(&message, data); err != nil {
+		log.Error().Err(err).Str("notifier", t.name).Msg("Failed to render message template")
+		message.Reset()
+		texttemplate.Must(texttemplate.New("text").Funcs(t.funcs()).Parse(defaultTextTemplate)).Execute(&message, data)
+	}
+	return strings.TrimSpace(message.String())
+}
+
+// HTML renders html message, default template is used on errors
+func (t *messageTemplates) HTML(updates []watcher.URLUpdate) string {
+	var message bytes.Buffer
+	data := t.data(updates)
+	if err := t.html.Execute(&message, data); err != nil {
+		log.Error().Err(err).Str("notifier", t.name).Msg("Failed to render html message template")
+		message.Reset()
+		htmltemplate.Must(htmltemplate.New("html").Funcs(t.funcs()).Parse(defaultHTMLTemplate)).Execute(&message, data)
+	}
+	return message.String()
+}
+
+// message renders text & html messages of updates
+func (t *messageTemplates) message(updates []watcher.URLUpdate) message {
+	return message{
+		Text:     t.Text(updates),
+		HTML:     t.HTML(updates),
+		Severity: batchSeverity(updates),
+		Failed:   hasFailures(updates),
+	}
+}
+
+// digest renders text & html messages of digest, default templates are used on errors
+func (t *messageTemplates) digest(d watcher.Digest) message {
+	data := digestData{Digest: d, Notifier: t.name, DashboardURL: t.dashboardURL}
+	var text, html bytes.Buffer
+	if err := t.digestText.Execute(&text, data); err != nil {
+		log.Error().Err(err).Str("notifier", t.name).Msg("Failed to render digest template")
+		text.Reset()
+		texttemplate.Must(texttemplate.New("digest").Funcs(t.funcs()).Parse(defaultDigestTemplate)).Execute(&text, data)
+	}
+	if err := t.digestHTML.Execute(&html, data); err != nil {
+		log.Error().Err(err).Str("notifier", t.name).Msg("Failed to render html digest template")
+		html.Reset()
+		htmltemplate.Must(htmltemplate.New("digestHTML").Funcs(t.funcs()).Parse(defaultDigestHTMLTemplate)).Execute(&html, data)
+	}
+	return message{
+		Title:    t.locale.T("digest", d.Name),
+		Text:     strings.TrimSpace(text.String()),
+		HTML:     html.String(),
+		Severity: watcher.SeverityInfo,
+	}
+}
@@ -2,8 +2,12 @@ package notifiers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html/template"
+	"strconv"
 	"sync"
+	"time"
 
 	"net/http"
 	"net/http/pprof"
@@ -37,19 +41,36 @@ func (n *WebNotifier) Run() {
 }
 
 // NewWebNotifier initialize web notifier instance
-func NewWebNotifier(cfg WebConfig, watcher watcher.Watcher) *WebNotifier {
-	return &WebNotifier{
-		server: NewServer(watcher, cfg.Port, cfg.Profiler),
+func NewWebNotifier(cfg WebConfig, w *watcher.Watcher) *WebNotifier {
+	locale := w.Locale()
+	if cfg.Timezone != "" || cfg.Language != "" {
+		language, timezone := locale.Language, locale.Location.String()
+		if cfg.Language != "" {
+			language = cfg.Language
+		}
+		if cfg.Timezone != "" {
+			timezone = cfg.Timezone
+		}
+		var err error
+		if locale, err = watcher.NewLocale(language, timezone); err != nil {
+			log.Fatal().Err(err).Msg("Invalid web locale settings")
+		}
 	}
+	notifier := &WebNotifier{
+		server: NewServer(w, cfg.Port, cfg.Profiler),
+	}
+	notifier.server.locale = locale
+	return notifier
 }
 
 // Server with rest api & static
 type Server struct {
-	watcher     watcher.Watcher
+	watcher     *watcher.Watcher
 	port        int
 	sockets     map[string]*websocket.Conn
 	upgrader    websocket.Upgrader
 	enablePprof bool
+	locale      watcher.Locale
 	mux         sync.RWMutex
 }
 
@@ -58,6 +79,15 @@ func (s *Server) Run() {
 	srv := http.NewServeMux()
 	srv.HandleFunc("/", s.index)
 	srv.HandleFunc("/api/list", s.list)
+	srv.HandleFunc("/api/tags", s.tags)
+	srv.HandleFunc("/api/maintenance", s.maintenance)
+	srv.HandleFunc("/api/outbox", s.outbox)
+	srv.HandleFunc("/api/escalations", s.escalations)
+	srv.HandleFunc("/api/ack", s.ack)
+	srv.HandleFunc("/api/digest", s.digest)
+	srv.HandleFunc("/ack", s.ackLink)
+	srv.HandleFunc("/api/v1/notifications", s.notifications)
+	srv.HandleFunc("/notifications", s.notificationsIndex)
 	srv.HandleFunc("/ws", s.upgrade)
 	if s.enablePprof {
 		srv.HandleFunc("/debug/pprof/", pprof.Index)
@@ -74,14 +104,296 @@ func (s *Server) Run() {
 }
 
 func (s *Server) index(w http.ResponseWriter, r *http.Request) {
-	w.Write([]byte(indexPageTemplate))
+	err := indexPage.Execute(w, indexPageData{
+		Language: s.locale.Language,
+		Timezone: s.locale.Location.String(),
+		Messages: s.locale.Messages(),
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to render index page")
+	}
 }
 
 func (s *Server) list(w http.ResponseWriter, r *http.Request) {
-	data, _ := json.Marshal(s.watcher.GetUrls())
+	query := r.URL.Query()
+	tag, group, link := query.Get("tag"), query.Get("group"), query.Get("url")
+	urls := make([]watcher.URL, 0)
+	for _, url := range s.watcher.GetUrls() {
+		if link != "" && url.Link != link && url.Name != link {
+			continue
+		}
+		if tag != "" && !url.HasTag(tag) {
+			continue
+		}
+		if group != "" && url.Group != group {
+			continue
+		}
+		urls = append(urls, url)
+	}
+	data, _ := json.Marshal(urls)
 	w.Write(data)
 }
 
+func (s *Server) tags(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.watcher.GetTags())
+}
+
+func (s *Server) maintenance(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.watcher.GetMaintenance())
+	case http.MethodPost:
+		var m watcher.Maintenance
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		m, err := s.watcher.AddMaintenance(m)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, m)
+	case http.MethodDelete:
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := s.watcher.RemoveMaintenance(id); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// outbox returns last notification messages,
+// filtered by notifier & status (pending, sent, dead)
+func (s *Server) outbox(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit := 100
+	if value := query.Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+			writeError(w, http.StatusBadRequest, errors.New("invalid limit"))
+			return
+		}
+	}
+	messages, err := s.watcher.GetOutbox(query.Get("notifier"), query.Get("status"), limit)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get outbox messages")
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, messages)
+}
+
+// escalations returns active escalations of incidents
+func (s *Server) escalations(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.watcher.GetEscalations())
+}
+
+// digest returns report of configured digest by name
+// or report of all urls for period in seconds, last day by default
+func (s *Server) digest(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	cfg := watcher.DigestConfig{Period: 86400}
+	if name := query.Get("name"); name != "" {
+		var ok bool
+		if cfg, ok = s.watcher.GetDigestConfig(name); !ok {
+			writeError(w, http.StatusNotFound, errors.New("unknown digest"))
+			return
+		}
+	} else if value := query.Get("period"); value != "" {
+		period, err := strconv.Atoi(value)
+		if err != nil || period <= 0 {
+			writeError(w, http.StatusBadRequest, errors.New("invalid period"))
+			return
+		}
+		cfg.Period = time.Duration(period)
+	}
+	digest, err := s.watcher.GetDigest(cfg, time.Now())
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to build digest")
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, digest)
+}
+
+// ackRequest acknowledges incident of url (link or name)
+type ackRequest struct {
+	URL string `json:"url"`
+	By  string `json:"by"`
+}
+
+// ackStatus returns response code of acknowledgement error
+func ackStatus(err error) int {
+	switch err {
+	case watcher.ErrUnknownURL:
+		return http.StatusNotFound
+	case watcher.ErrNoIncident:
+		return http.StatusConflict
+	case watcher.ErrInvalidAckToken:
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+// ack acknowledges incident of url
+func (s *Server) ack(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var req ackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	url, err := s.watcher.Acknowledge(req.URL, req.By)
+	if err != nil {
+		writeError(w, ackStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, url)
+}
+
+// ackLink shows confirmation of signed acknowledgement link asking name of user,
+// incident is acknowledged on form submit, so link previews don't acknowledge it
+func (s *Server) ackLink(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	data := ackPageData{
+		Language: s.locale.Language,
+		URL:      r.Form.Get("url"),
+		By:       r.Form.Get("by"),
+		Token:    r.Form.Get("token"),
+	}
+	code := http.StatusOK
+	switch r.Method {
+	case http.MethodGet:
+		data.Message = s.locale.T("ack_confirm", data.URL)
+	case http.MethodPost:
+		url, err := s.watcher.AcknowledgeSigned(data.URL, data.By, data.Token)
+		data.Done = true
+		switch err {
+		case nil:
+			data.Message = s.locale.T("ack_done", url.Name, url.AckedBy)
+		case watcher.ErrUnknownURL, watcher.ErrInvalidAckToken:
+			data.Message = s.locale.T("ack_invalid")
+		case watcher.ErrNoIncident:
+			data.Message = s.locale.T("ack_no_incident", url.Name)
+		default:
+			data.Message = err.Error()
+		}
+		if err != nil {
+			code = ackStatus(err)
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	page := template.Must(ackPage.Clone())
+	w.WriteHeader(code)
+	err := page.Funcs(template.FuncMap{"t": s.locale.T}).Execute(w, data)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to render ack page")
+	}
+}
+
+// notificationsData describes notifier health & last delivery attempts
+type notificationsData struct {
+	Notifiers  []watcher.NotifierHealth `json:"notifiers"`
+	Deliveries []watcher.Delivery       `json:"deliveries"`
+}
+
+// errInvalidLimit is returned for invalid limit query parameter
+var errInvalidLimit = errors.New("invalid limit")
+
+func (s *Server) notificationsData(r *http.Request) (notificationsData, error) {
+	query := r.URL.Query()
+	limit := 100
+	if value := query.Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+			return notificationsData{}, errInvalidLimit
+		}
+	}
+	var (
+		data notificationsData
+		err  error
+	)
+	if data.Notifiers, err = s.watcher.GetNotifierHealth(); err != nil {
+		return data, err
+	}
+	data.Deliveries, err = s.watcher.GetDeliveries(query.Get("notifier"), limit)
+	return data, err
+}
+
+// notificationsError responds with bad request for invalid parameters
+// and internal error for storage failures
+func notificationsError(w http.ResponseWriter, err error) {
+	if err == errInvalidLimit {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	log.Error().Err(err).Msg("Failed to get notifications")
+	writeError(w, http.StatusInternalServerError, err)
+}
+
+// notifications returns notifier health & last delivery attempts,
+// deliveries are filtered by notifier
+func (s *Server) notifications(w http.ResponseWriter, r *http.Request) {
+	data, err := s.notificationsData(r)
+	if err != nil {
+		notificationsError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, data)
+}
+
+func (s *Server) notificationsIndex(w http.ResponseWriter, r *http.Request) {
+	data, err := s.notificationsData(r)
+	if err != nil {
+		notificationsError(w, err)
+		return
+	}
+	// funcs of shared template are replaced on copy to use server locale
+	page := template.Must(notificationsPage.Clone())
+	err = page.Funcs(template.FuncMap{
+		"formatTime": s.locale.Time,
+		"t":          s.locale.T,
+	}).Execute(w, notificationsPageData{
+		Language:          s.locale.Language,
+		notificationsData: data,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to render notifications page")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to encode response")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
 func (s *Server) upgrade(w http.ResponseWriter, r *http.Request) {
 	c, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -125,7 +437,7 @@ func (s *Server) Broadcast(message []byte) {
 }
 
 // NewServer returns new web server
-func NewServer(w watcher.Watcher, port int, enablePprof bool) Server {
+func NewServer(w *watcher.Watcher, port int, enablePprof bool) Server {
 	return Server{
 		watcher:     w,
 		port:        port,
@@ -135,9 +447,145 @@ func NewServer(w watcher.Watcher, port int, enablePprof bool) Server {
 	}
 }
 
+// indexPageData is passed to index page template
+type indexPageData struct {
+	Language string
+	Timezone string
+	Messages map[string]string
+}
+
+// ackPageData is passed to acknowledgement page template
+type ackPageData struct {
+	Language string
+	URL      string
+	By       string
+	Token    string
+	Message  string
+	// Done is true after form is submitted
+	Done bool
+}
+
+var ackPage = template.Must(template.New("ack").Funcs(template.FuncMap{
+	"t": watcher.Locale{}.T,
+}).Parse(ackPageTemplate))
+
+const ackPageTemplate = `
+<!doctype html>
+<html lang="{{ .Language }}">
+  <head>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1, shrink-to-fit=no">
+    <title>{{ t "ack" }}</title>
+    <link rel="stylesheet" href="https://maxcdn.bootstrapcdn.com/bootstrap/4.0.0/css/bootstrap.min.css" integrity="sha384-Gn5384xqQ1aoWXA+058RXPxPg6fy4IWvTNh0E263XmFcJlSAwiGgFAW/dAiS6JXm" crossorigin="anonymous">
+  </head>
+  <body>
+      <div class="container my-3">
+          <p>{{ .Message }}</p>
+          {{- if not .Done }}
+          <form method="post">
+              <input type="hidden" name="url" value="{{ .URL }}">
+              <input type="hidden" name="token" value="{{ .Token }}">
+              <div class="form-group">
+                  <label for="by">{{ t "ack_prompt" }}</label>
+                  <input type="text" class="form-control" id="by" name="by" value="{{ .By }}" maxlength="200" required>
+              </div>
+              <button type="submit" class="btn btn-primary">{{ t "ack" }}</button>
+          </form>
+          {{- end }}
+          <a href="./">{{ t "dashboard" }}</a>
+      </div>
+  </body>
+</html>
+`
+
+// notificationsPageData is passed to notifications page template
+type notificationsPageData struct {
+	Language string
+	notificationsData
+}
+
+var notificationsPage = template.Must(template.New("notifications").Funcs(template.FuncMap{
+	"formatTime": watcher.Locale{}.Time,
+	"t":          watcher.Locale{}.T,
+}).Parse(notificationsPageTemplate))
+
+const notificationsPageTemplate = `
+<!doctype html>
+<html lang="{{ .Language }}">
+  <head>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1, shrink-to-fit=no">
+    <title>{{ t "notifications" }}</title>
+    <link rel="stylesheet" href="https://maxcdn.bootstrapcdn.com/bootstrap/4.0.0/css/bootstrap.min.css" integrity="sha384-Gn5384xqQ1aoWXA+058RXPxPg6fy4IWvTNh0E263XmFcJlSAwiGgFAW/dAiS6JXm" crossorigin="anonymous">
+  </head>
+  <body>
+      <div class="container">
+          <div class="row my-3">
+              <a href="./">{{ t "dashboard" }}</a>
+          </div>
+          <h4>{{ t "notifications" }}</h4>
+          <table class="table">
+              <thead>
+                  <tr>
+                      <th scope="col">{{ t "notifier" }}</th>
+                      <th scope="col">{{ t "status_column" }}</th>
+                      <th scope="col">{{ t "attempts" }}</th>
+                      <th scope="col">{{ t "failures" }}</th>
+                      <th scope="col">{{ t "last_success" }}</th>
+                      <th scope="col">{{ t "last_failure" }}</th>
+                      <th scope="col">{{ t "error" }}</th>
+                  </tr>
+              </thead>
+              <tbody>
+              {{- range .Notifiers }}
+                  <tr{{ if not .Healthy }} class="table-danger"{{ end }}>
+                      <td><a href="?notifier={{ .Notifier }}">{{ .Notifier }}</a></td>
+                      <td>{{ if .Healthy }}<span class="badge badge-success">{{ t "healthy" }}</span>
+                          {{- else }}<span class="badge badge-danger">{{ t "failing" }}</span>{{ end }}</td>
+                      <td>{{ .Attempts }}</td>
+                      <td>{{ .Failures }}</td>
+                      <td>{{ if not .LastSuccess.IsZero }}{{ formatTime .LastSuccess }}{{ end }}</td>
+                      <td>{{ if not .LastFailure.IsZero }}{{ formatTime .LastFailure }}{{ end }}</td>
+                      <td>{{ .LastError }}</td>
+                  </tr>
+              {{- end }}
+              </tbody>
+          </table>
+          <h4>{{ t "deliveries" }}</h4>
+          <table class="table table-sm">
+              <thead>
+                  <tr>
+                      <th scope="col">{{ t "time" }}</th>
+                      <th scope="col">{{ t "notifier" }}</th>
+                      <th scope="col">{{ t "target" }}</th>
+                      <th scope="col">{{ t "response_code" }}</th>
+                      <th scope="col">{{ t "latency" }}</th>
+                      <th scope="col">{{ t "error" }}</th>
+                  </tr>
+              </thead>
+              <tbody>
+              {{- range .Deliveries }}
+                  <tr{{ if .Error }} class="table-danger"{{ end }}>
+                      <td>{{ formatTime .Time }}</td>
+                      <td>{{ .Notifier }}</td>
+                      <td>{{ .Target }}</td>
+                      <td>{{ if .ResponseCode }}{{ .ResponseCode }}{{ end }}</td>
+                      <td>{{ .Latency }}</td>
+                      <td>{{ .Error }}</td>
+                  </tr>
+              {{- end }}
+              </tbody>
+          </table>
+      </div>
+  </body>
+</html>
+`
+
+var indexPage = template.Must(template.New("index").Parse(indexPageTemplate))
+
 const indexPageTemplate = `
 <!doctype html>
-<html lang="en">
+<html lang="{{ .Language }}">
   <head>
     <meta charset="utf-8">
     <meta name="viewport" content="width=device-width, initial-scale=1, shrink-to-fit=no">
@@ -154,25 +602,41 @@ const indexPageTemplate = `
   </head>
   <body>
       <div class="container">
+          <div class="row my-3">
+                <select class="form-control col-3 tag_filter">
+                    <option value="">{{ index .Messages "all_tags" }}</option>
+                </select>
+                <a class="ml-auto" href="notifications">{{ index .Messages "notifications" }}</a>
+          </div>
           <div class="row">
                 <table class="table">
                     <thead>
                         <tr>
                             <th scope="col">#</th>
-                            <th scope="col">Url</th>
-                            <th scope="col">Last change</th>
-                            <th scope="col">Status</th>
+                            <th scope="col">{{ index .Messages "url" }}</th>
+                            <th scope="col">{{ index .Messages "tags" }}</th>
+                            <th scope="col">{{ index .Messages "last_change" }}</th>
+                            <th scope="col">{{ index .Messages "status_column" }}</th>
                         </tr>
                     </thead>
                     <tbody>
+                        <tr class="d-none empty_group">
+                            <th colspan="5" class="table-active">
+                                <span class="name"></span>
+                                <span class="badge health"></span>
+                            </th>
+                        </tr>
                         <tr class="d-none empty_row">
                             <th scope="row" class="num"></th>
                             <td class="url">
                                 <a href=""></a>
                             </td>
+                            <td class="tags"></td>
                             <td class="change"></td>
                             <td class="status">
                                 <span class="dot"></span>
+                                <small class="acked text-muted"></small>
+                                <button type="button" class="btn btn-sm btn-outline-secondary ack d-none">{{ index .Messages "ack" }}</button>
                             </td>
 
                         </tr>
@@ -186,33 +650,117 @@ const indexPageTemplate = `
     <script src="https://cdnjs.cloudflare.com/ajax/libs/popper.js/1.12.9/umd/popper.min.js" integrity="sha384-ApNbgh9B+Y1QKtv3Rn7W3mgPxhU9K/ScQsAP7hUibX39j7fakFPskvXusvfa0b4Q" crossorigin="anonymous"></script>
     <script src="https://maxcdn.bootstrapcdn.com/bootstrap/4.0.0/js/bootstrap.min.js" integrity="sha384-JZR6Spejh4U02d8jOt6vLEHfe/JQGiRRSQQxSfFWpi1MquVdAyjUar5+76PVCmYl" crossorigin="anonymous"></script>
     <script>
+        const language = {{ .Language }};
+        const timeZone = {{ .Timezone }};
+        const messages = {{ .Messages }};
+        // t returns translated message with %s & %d replaced by args
+        function t(key) {
+            let args = Array.prototype.slice.call(arguments, 1);
+            return messages[key].replace(/%[sd]/g, function() {
+                return args.shift();
+            });
+        }
+        function statusColor(data) {
+            return data.unreachable_via ? 'gray' : 'red';
+        }
+        function errorText(data) {
+            let text = "";
+            if (data.error != "") {
+                text = data.error
+            } else {
+                text = t('status', data.status);
+            }
+            if (data.unreachable_via) {
+                text = t('unreachable', data.unreachable_via) + ': ' + text;
+            }
+            return text;
+        }
+        function isGood(data) {
+            return data.error == "" && data.status == 200;
+        }
+        function renderStatus(row, data) {
+            let dot = row.find('.status .dot');
+            let changed = new Date(data.last_change);
+            row.find('.change').text(changed.toLocaleString(language, {timeZone: timeZone}));
+            row.toggleClass('good', isGood(data));
+            row.find('.acked').text(data.acked_by ? t('acked_by', data.acked_by) : '');
+            row.find('.ack').toggleClass('d-none', isGood(data) || !!data.acked_by).data('url', data.url);
+            if (isGood(data)) {
+                dot.css('background-color', 'green');
+                dot.popover('disable');
+            } else {
+                dot.css('background-color', statusColor(data));
+                dot.attr('data-content', errorText(data));
+                dot.popover({trigger: 'hover'});
+                dot.popover('enable');
+            }
+        }
+        function updateGroupHealth(group) {
+            let rows = $('tr.url_row').filter(function() {
+                return $(this).data('group') === group;
+            });
+            let good = rows.filter('.good').length;
+            let health = $('tr.group_row').filter(function() {
+                return $(this).data('group') === group;
+            }).find('.health');
+            health.text(t('group_health', good, rows.length));
+            health.toggleClass('badge-success', good == rows.length);
+            health.toggleClass('badge-danger', good != rows.length);
+        }
         $(document).ready(function() {
-            $.get('api/list', function(data) {
+            let params = new URLSearchParams(window.location.search);
+            $('table').on('click', '.ack', function() {
+                let by = window.prompt(t('ack_prompt'), localStorage.getItem('ack_by') || '');
+                if (by === null) {
+                    return;
+                }
+                localStorage.setItem('ack_by', by);
+                $.ajax({
+                    url: 'api/ack',
+                    method: 'POST',
+                    contentType: 'application/json',
+                    data: JSON.stringify({url: $(this).data('url'), by: by}),
+                    error: function(xhr) {
+                        window.alert(xhr.responseJSON ? xhr.responseJSON.error : xhr.statusText);
+                    }
+                });
+            });
+            $.get('api/tags', function(tags) {
+                let select = $('.tag_filter');
+                for (var idx = 0; idx < tags.length; idx++) {
+                    select.append($('<option>').text(tags[idx]).attr('value', tags[idx]));
+                }
+                select.val(params.get('tag') || '');
+                select.change(function() {
+                    window.location.search = select.val() ? '?tag=' + encodeURIComponent(select.val()) : '';
+                });
+            });
+            $.get('api/list' + window.location.search, function(data) {
                 let tbody = $('table tbody');
                 data = JSON.parse(data);
+                data.sort(function(a, b) {
+                    return a.group.localeCompare(b.group);
+                });
+                let groups = [];
                 for (var idx = 0; idx < data.length; idx++) {
-                    tbody.append($('.empty_row').clone());
-                    row = $($('.empty_row')[1]);
-                    row.attr('class', '');
-                    row.find('.num').text(1 + idx);
-                    row.find('.url a').text(data[idx].url).attr('href', data[idx].url);
-                    let changed = new Date(data[idx].last_change);
-                    row.find('.change').text(changed.toLocaleString());
-                    if (data[idx]['error'] == "" && data[idx]['status'] == 200) {
-                        row.find('.status .dot').css('background-color', 'green');
-                    } else {
-                        row.find('.status .dot').css('background-color', 'red');
-                        let text = "";
-                        if (data[idx].error != "") {
-                            text = data[idx].error
-                        } else {
-                            text = 'Status ' + data[idx].status;
-                        }
-                        row.find('.status .dot').popover({
-                            content: text,
-                            trigger: 'hover',
-                        });
+                    let group = data[idx].group;
+                    if (groups.indexOf(group) == -1) {
+                        groups.push(group);
+                        let header = $('.empty_group').first().clone();
+                        header.attr('class', 'group_row').data('group', group);
+                        header.find('.name').text(group || t('ungrouped'));
+                        tbody.append(header);
                     }
+                    let row = $('.empty_row').first().clone();
+                    row.attr('class', 'url_row').data('group', group);
+                    row.find('.num').text(1 + idx);
+                    row.find('.url a').text(data[idx].name).attr('href', data[idx].url);
+                    row.find('.tags').text((data[idx].tags || []).join(', '));
+                    tbody.append(row);
+                    renderStatus(row, data[idx]);
+                }
+                for (var idx = 0; idx < groups.length; idx++) {
+                    updateGroupHealth(groups[idx]);
                 }
                 url = new URL(window.location.href);
                 url.protocol = 'ws:';
@@ -230,23 +778,11 @@ const indexPageTemplate = `
                 ws.onmessage = function(evt) {
                     data = JSON.parse(evt.data);
                     let row = $('a[href="' +data.url+'"]').parents('tr');
-                    changed = new Date(data.last_change);
-                    row.find('.change').text(changed.toLocaleString());
-                    if (data['error'] === "" && data["status"] === 200) {
-                        row.find('.status .dot').css('background-color', 'green');
-                        row.find('.status .dot').popover('disable');
-                    } else {
-                        row.find('.status .dot').css('background-color', 'red');
-                        let text = "";
-                        if (data.error != "") {
-                            text = data.error
-                        } else {
-                            text = 'Status ' + data.status;
-                        }
-                        row.find('.status .dot').popover({trigger: 'hover'});
-                        row.find('.status .dot').popover('enable');
-                        row.find('.status .dot').attr('data-content', text);
+                    if (row.length == 0) {
+                        return;
                     }
+                    renderStatus(row, data);
+                    updateGroupHealth(data.group);
                 }
                 ws.onerror = function(evt) {
                     console.log("ws ERROR: " + evt.data);
@@ -0,0 +1,176 @@
+package notifiers
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"text/template"
+
+	"github.com/rbhz/web_watcher/watcher"
+	"github.com/rs/zerolog/log"
+)
+
+const webhookDefaultBody = `{{ json .Updates }}`
+
+var webhookTemplateFuncs = template.FuncMap{
+	"json": func(value interface{}) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+}
+
+// webhookData is passed to body & headers templates,
+// Update contains first update of batch, Digest is set for digest reports
+type webhookData struct {
+	Update  watcher.URLUpdate
+	Updates []watcher.URLUpdate
+	Digest  *watcher.Digest
+}
+
+// WebhookNotifier sends updates to configured url
+type WebhookNotifier struct {
+	url             string
+	method          string
+	body            *template.Template
+	headers         map[string]*template.Template
+	secret          []byte
+	signatureHeader string
+	batch           bool
+	baseMessageNotifier
+}
+
+// Notify sends update immediately or adds it to batch,
+// both modes receive every update including content changes
+func (n *WebhookNotifier) Notify(update watcher.URLUpdate) {
+	if n.batch {
+		n.mux.Lock()
+		n.updates = append(n.updates, update)
+		n.mux.Unlock()
+		return
+	}
+	n.dispatch([]watcher.URLUpdate{update})
+}
+
+// Run sends batches if batching is enabled & retries failed requests
+func (n *WebhookNotifier) Run() {
+	if n.batch {
+		n.baseMessageNotifier.Run()
+	} else {
+		n.baseNotifier.Run()
+	}
+}
+
+func (n *WebhookNotifier) render(tmpl *template.Template, data webhookData) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (n *WebhookNotifier) sign(body []byte) string {
+	mac := hmac.New(sha256.New, n.secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (n *WebhookNotifier) sendUpdates(m watcher.OutboxMessage) error {
+	n.log(log.Info).Int("count", len(m.Updates)).Msg("sending webhook")
+	if len(m.Updates) == 0 {
+		return errNoUpdates
+	}
+	data := webhookData{Update: m.Updates[0], Updates: m.Updates}
+	body, err := n.render(n.body, data)
+	if err != nil {
+		n.log(log.Error).Err(err).Msg("Failed to render body")
+		return err
+	}
+	headers, err := n.renderHeaders(data, body)
+	if err != nil {
+		return err
+	}
+	return n.post(body, headers)
+}
+
+// NotifyDigest sends digest report as JSON, header templates receive it as .Digest
+func (n *WebhookNotifier) NotifyDigest(d watcher.Digest) {
+	n.log(log.Info).Str("digest", d.Name).Msg("Sending digest")
+	body, err := json.Marshal(d)
+	if err != nil {
+		n.log(log.Error).Err(err).Msg("Failed to encode digest")
+		return
+	}
+	headers, err := n.renderHeaders(webhookData{Digest: &d}, body)
+	if err == nil {
+		err = n.post(body, headers)
+	}
+	if err != nil {
+		n.log(log.Error).Err(err).Str("digest", d.Name).Msg("Failed to send digest")
+	}
+}
+
+// renderHeaders renders header templates & signs body if secret is set
+func (n *WebhookNotifier) renderHeaders(data webhookData, body []byte) (map[string]string, error) {
+	headers := make(map[string]string, len(n.headers)+1)
+	for name, tmpl := range n.headers {
+		value, err := n.render(tmpl, data)
+		if err != nil {
+			n.log(log.Error).Err(err).Str("header", name).Msg("Failed to render header")
+			return nil, err
+		}
+		headers[name] = string(value)
+	}
+	if len(n.secret) != 0 {
+		headers[n.signatureHeader] = n.sign(body)
+	}
+	return headers, nil
+}
+
+func (n *WebhookNotifier) post(body []byte, headers map[string]string) error {
+	req, err := http.NewRequest(n.method, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	return n.doRequest(req, body)
+}
+
+// NewWebhookNotifier creates notifier
+func NewWebhookNotifier(cfg WebhookConfig) *WebhookNotifier {
+	if cfg.URL == "" {
+		log.Fatal().Str("notifier", cfg.Name).Msg("Specify webhook url")
+	}
+	if cfg.Body == "" {
+		cfg.Body = webhookDefaultBody
+	}
+	body, err := template.New("body").Funcs(webhookTemplateFuncs).Parse(cfg.Body)
+	if err != nil {
+		log.Fatal().Err(err).Str("notifier", cfg.Name).Msg("Invalid webhook body template")
+	}
+	headers := make(map[string]*template.Template, len(cfg.Headers))
+	for name, value := range cfg.Headers {
+		headers[name], err = template.New(name).Funcs(webhookTemplateFuncs).Parse(value)
+		if err != nil {
+			log.Fatal().Err(err).Str("notifier", cfg.Name).Str("header", name).Msg("Invalid webhook header template")
+		}
+	}
+	notifier := WebhookNotifier{
+		url:             cfg.URL,
+		method:          cfg.Method,
+		body:            body,
+		headers:         headers,
+		secret:          []byte(cfg.Secret),
+		signatureHeader: cfg.SignatureHeader,
+		batch:           cfg.Batch,
+		baseMessageNotifier: baseMessageNotifier{
+			baseNotifier:  newBaseNotifier(cfg.Name, cfg.Routes),
+			messagePeriod: cfg.MessagePeriod}}
+	notifier.sendFunc = notifier.sendUpdates
+	return &notifier
+}
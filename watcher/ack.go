@@ -0,0 +1,109 @@
+package watcher
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Acknowledgement errors
+var (
+	ErrUnknownURL      = errors.New("unknown url")
+	ErrNoIncident      = errors.New("url is not down")
+	ErrInvalidAckToken = errors.New("invalid acknowledgement token")
+)
+
+// ackRequest is applied by watcher loop, so checks don't overwrite acknowledgement
+type ackRequest struct {
+	link   string
+	by     string
+	token  string
+	signed bool
+	reply  chan ackResult
+}
+
+type ackResult struct {
+	url    URL
+	err    error
+	update *URLUpdate
+}
+
+// Acknowledge marks active incident of url (link or name) as acknowledged,
+// which stops reminders & escalations until url recovers
+func (w *Watcher) Acknowledge(link, by string) (URL, error) {
+	return w.requestAck(ackRequest{link: link, by: by})
+}
+
+// AcknowledgeSigned acknowledges incident using signed link token
+func (w *Watcher) AcknowledgeSigned(link, by, token string) (URL, error) {
+	return w.requestAck(ackRequest{link: link, by: by, token: token, signed: true})
+}
+
+func (w *Watcher) requestAck(req ackRequest) (URL, error) {
+	req.reply = make(chan ackResult, 1)
+	w.acks <- req
+	result := <-req.reply
+	return result.url, result.err
+}
+
+// acknowledge applies acknowledgement request, it's called by watcher loop only
+func (w *Watcher) acknowledge(req ackRequest) ackResult {
+	var url *URL
+	for _, u := range w.urls {
+		if u.Link == req.link || (!req.signed && u.Name == req.link) {
+			url = u
+			break
+		}
+	}
+	if url == nil {
+		return ackResult{err: ErrUnknownURL}
+	}
+	if req.signed {
+		expected := w.AckToken(*url)
+		if expected == "" || !hmac.Equal([]byte(expected), []byte(req.token)) {
+			return ackResult{url: *url, err: ErrInvalidAckToken}
+		}
+	}
+	if url.Good() {
+		return ackResult{url: *url, err: ErrNoIncident}
+	}
+	if url.AckedBy != "" {
+		return ackResult{url: *url}
+	}
+	by := req.by
+	if by == "" {
+		by = "anonymous"
+	}
+	old := *url
+	w.urlMux.Lock()
+	url.AckedBy, url.AckedAt = by, time.Now()
+	w.urlMux.Unlock()
+	_, err := w.db.Exec("UPDATE urls SET acked_by=?, acked_at=? WHERE link=?", url.AckedBy, url.AckedAt, url.Link)
+	if err != nil {
+		url.log(log.Error).Err(err).Msg("Failed to save acknowledgement")
+	}
+	url.log(log.Info).Str("by", by).Msg("Incident acknowledged")
+	return ackResult{url: *url, update: &URLUpdate{
+		New:        *url,
+		Old:        old,
+		Changed:    []int{AckChange},
+		Created:    url.AckedAt,
+		Dependents: w.dependents(url.id),
+	}}
+}
+
+// AckToken returns signature of acknowledgement link of current url incident,
+// it's empty if ack secret is not configured
+func (w *Watcher) AckToken(u URL) string {
+	if len(w.ackSecret) == 0 || u.DownSince.IsZero() {
+		return ""
+	}
+	mac := hmac.New(sha256.New, w.ackSecret)
+	mac.Write([]byte(u.Link + "\n" + strconv.FormatInt(u.DownSince.Unix(), 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
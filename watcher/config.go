@@ -6,5 +6,16 @@ import "time"
 type Config struct {
 	Period      time.Duration `default:"10"`
 	ErrorPeriod time.Duration `default:"1"`
-	DBPath      string `default:"./.watcher.db"`
+	DBPath      string        `default:"./.watcher.db"`
+	// Timezone & Language are defaults of notifiers & web ui
+	Timezone    string `default:"UTC"`
+	Language    string `default:"en"`
+	Maintenance []Maintenance
+	Escalations []EscalationPolicy
+	Digests     []DigestConfig
+	// HistoryRetention in seconds check stats & incidents are kept for digests
+	HistoryRetention time.Duration `default:"2678400"`
+	// AckSecret signs acknowledgement links, links are disabled if it's empty
+	AckSecret string
+	Outbox    OutboxConfig
 }
@@ -0,0 +1,143 @@
+package watcher
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// healthWindow is period of deliveries used for notifier health stats
+const healthWindow = 24 * time.Hour
+
+// Delivery describes single notification delivery attempt
+type Delivery struct {
+	ID       int64     `json:"id"`
+	Notifier string    `json:"notifier"`
+	Time     time.Time `json:"time"`
+	// Target is service address, recipient or path
+	Target       string        `json:"target"`
+	PayloadHash  string        `json:"payload_hash"`
+	ResponseCode int           `json:"response_code"`
+	Error        string        `json:"error"`
+	Latency      time.Duration `json:"latency"`
+}
+
+// NotifierHealth summarizes recent deliveries of notifier
+type NotifierHealth struct {
+	Notifier string `json:"notifier"`
+	// Healthy is false if last deliveries failed
+	Healthy             bool      `json:"healthy"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	Attempts            int       `json:"attempts"`
+	Failures            int       `json:"failures"`
+	LastSuccess         time.Time `json:"last_success"`
+	LastFailure         time.Time `json:"last_failure"`
+	LastError           string    `json:"last_error"`
+}
+
+// RecordDelivery stores delivery attempt,
+// notifier is reported as failing after configured number of failures in a row
+func (w *Watcher) RecordDelivery(d Delivery) {
+	_, err := w.db.Exec(
+		"INSERT INTO deliveries (notifier, time, target, payload_hash, response_code, error, latency) "+
+			"VALUES(?, ?, ?, ?, ?, ?, ?)",
+		d.Notifier, d.Time, d.Target, d.PayloadHash, d.ResponseCode, d.Error, int64(d.Latency))
+	if err != nil {
+		log.Error().Err(err).Str("notifier", d.Notifier).Msg("Failed to save delivery")
+	}
+	w.deliveryMux.Lock()
+	defer w.deliveryMux.Unlock()
+	if d.Error == "" {
+		if w.deliveryFailures[d.Notifier] >= w.outbox.UnhealthyAfter {
+			log.Info().Str("notifier", d.Notifier).Msg("Notifier deliveries recovered")
+		}
+		delete(w.deliveryFailures, d.Notifier)
+		return
+	}
+	w.deliveryFailures[d.Notifier]++
+	if w.deliveryFailures[d.Notifier] == w.outbox.UnhealthyAfter {
+		log.Warn().Str("notifier", d.Notifier).Str("error", d.Error).Msg("Notifier deliveries are failing")
+	}
+}
+
+// GetDeliveries returns last delivery attempts, filtered by notifier if it's set
+func (w *Watcher) GetDeliveries(notifier string, limit int) ([]Delivery, error) {
+	rows, err := w.db.Query(
+		"SELECT id, notifier, time, target, payload_hash, response_code, error, latency FROM deliveries "+
+			"WHERE (?='' OR notifier=?) ORDER BY id DESC LIMIT ?;",
+		notifier, notifier, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	deliveries := make([]Delivery, 0)
+	for rows.Next() {
+		var (
+			d       Delivery
+			latency int64
+		)
+		err := rows.Scan(&d.ID, &d.Notifier, &d.Time, &d.Target, &d.PayloadHash, &d.ResponseCode, &d.Error, &latency)
+		if err != nil {
+			return nil, err
+		}
+		d.Latency = time.Duration(latency)
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// GetNotifierHealth returns delivery stats of notifiers for the last day
+func (w *Watcher) GetNotifierHealth() ([]NotifierHealth, error) {
+	rows, err := w.db.Query(
+		"SELECT notifier, time, error FROM deliveries WHERE time>? ORDER BY notifier, id DESC;",
+		time.Now().Add(-healthWindow))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := make([]NotifierHealth, 0)
+	var (
+		health    *NotifierHealth
+		succeeded bool
+	)
+	for rows.Next() {
+		var (
+			notifier, errText string
+			t                 time.Time
+		)
+		if err := rows.Scan(&notifier, &t, &errText); err != nil {
+			return nil, err
+		}
+		if health == nil || health.Notifier != notifier {
+			res = append(res, NotifierHealth{Notifier: notifier})
+			health, succeeded = &res[len(res)-1], false
+		}
+		health.Attempts++
+		if errText == "" {
+			succeeded = true
+			if health.LastSuccess.IsZero() {
+				health.LastSuccess = t
+			}
+			continue
+		}
+		health.Failures++
+		if !succeeded {
+			health.ConsecutiveFailures++
+		}
+		if health.LastFailure.IsZero() {
+			health.LastFailure, health.LastError = t, errText
+		}
+	}
+	for idx := range res {
+		res[idx].Healthy = res[idx].ConsecutiveFailures < w.outbox.UnhealthyAfter
+	}
+	return res, rows.Err()
+}
+
+// cleanDeliveries removes deliveries older than retention
+func (w *Watcher) cleanDeliveries() {
+	_, err := w.db.Exec("DELETE FROM deliveries WHERE time<?", time.Now().Add(-w.outbox.Retention*time.Second))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to clean deliveries")
+	}
+}
@@ -0,0 +1,151 @@
+package watcher
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// downParent returns first parent url which is not available
+func (u *URL) downParent() *URL {
+	for _, parent := range u.parents {
+		if !parent.Good() {
+			return parent
+		}
+	}
+	return nil
+}
+
+// dependsOn returns true if url directly or indirectly depends on target
+func (u *URL) dependsOn(target *URL, visited map[*URL]bool) bool {
+	for _, parent := range u.parents {
+		if parent == target {
+			return true
+		}
+		if visited[parent] {
+			continue
+		}
+		visited[parent] = true
+		if parent.dependsOn(target, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *Watcher) resolveParents() {
+	index := make(map[string]*URL)
+	for _, url := range w.urls {
+		index[url.Link] = url
+		index[url.Name] = url
+	}
+	for _, url := range w.urls {
+		for _, ref := range url.Parents {
+			parent, ok := index[ref]
+			if !ok {
+				log.Fatal().Str("url", url.Link).Str("parent", ref).Msg("Unknown parent url")
+			}
+			url.parents = append(url.parents, parent)
+		}
+	}
+	for _, url := range w.urls {
+		if url.dependsOn(url, make(map[*URL]bool)) {
+			log.Fatal().Str("url", url.Link).Msg("Circular url dependency")
+		}
+	}
+}
+
+// dependents returns links of urls depending on url with given id,
+// failed url lists all of them as they aren't rechecked yet when its failure is reported,
+// recovered url lists urls which were unreachable due to it
+func (w *Watcher) dependents(id int) (links []string) {
+	target := w.getURLByID(id)
+	if target == nil {
+		return
+	}
+	for _, url := range w.urls {
+		if (!target.Good() || url.UnreachableVia != "") && url.dependsOn(target, make(map[*URL]bool)) {
+			links = append(links, url.Link)
+		}
+	}
+	return
+}
+
+// deferToParents holds failure of url with parents until parents are rechecked,
+// so failure caused by parent which isn't detected yet is reported within parent notification
+func (w *Watcher) deferToParents(update URLUpdate) bool {
+	id := update.Old.id
+	if deferred, ok := w.deferred[id]; ok {
+		if update.New.Good() {
+			// failure wasn't reported, so recovery isn't reported too
+			delete(w.deferred, id)
+		} else {
+			update.Old = deferred.Old
+			w.deferred[id] = update
+		}
+		return true
+	}
+	url := w.getURLByID(id)
+	if url == nil || len(url.parents) == 0 || !update.Old.Good() || update.New.Good() {
+		return false
+	}
+	w.deferred[id] = update
+	w.urlMux.Lock()
+	for _, parent := range url.parents {
+		parent.lastCheck = time.Time{}
+	}
+	w.urlMux.Unlock()
+	return true
+}
+
+// releaseDeferred reports deferred failures of urls which parents are checked & available,
+// failures of urls which parent is down are suppressed & urls are rechecked
+func (w *Watcher) releaseDeferred(notifiers []Notifier) {
+	for id, update := range w.deferred {
+		url := w.getURLByID(id)
+		if parent := url.downParent(); parent != nil {
+			delete(w.deferred, id)
+			w.parentSuppressed[id] = true
+			w.urlMux.Lock()
+			url.lastCheck = time.Time{}
+			w.urlMux.Unlock()
+			log.Info().Str("url", url.Link).Str("parent", parent.Link).Msg("Notification suppressed by parent")
+			continue
+		}
+		checked := true
+		for _, parent := range url.parents {
+			checked = checked && parent.lastCheck.After(update.Created)
+		}
+		if checked {
+			delete(w.deferred, id)
+			update.Dependents = w.dependents(id)
+			w.notify(notifiers, update)
+		}
+	}
+}
+
+// suppressedByParent returns true if update is caused by parent url change,
+// such updates are reported once within parent notification.
+// Failure which was suppressed is reported if url is still down after parent
+// recovered, recovery is suppressed only if failure was suppressed too.
+func (w *Watcher) suppressedByParent(update *URLUpdate) bool {
+	id := update.Old.id
+	if update.New.UnreachableVia != "" {
+		if update.Old.Good() {
+			w.parentSuppressed[id] = true
+		}
+		return true
+	}
+	if update.New.Good() {
+		suppressed := w.parentSuppressed[id] && !update.Old.Good()
+		delete(w.parentSuppressed, id)
+		return suppressed
+	}
+	if update.Old.UnreachableVia != "" && w.parentSuppressed[id] {
+		// report failure as new one
+		delete(w.parentSuppressed, id)
+		update.Old.Status, update.Old.Err, update.Old.UnreachableVia = http.StatusOK, "", ""
+	}
+	return false
+}
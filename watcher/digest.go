@@ -0,0 +1,295 @@
+package watcher
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// defaultSlowest is number of slowest monitors in digest
+const defaultSlowest = 5
+
+// DigestConfig describes periodic summary report sent to notifiers
+type DigestConfig struct {
+	Name string
+	// Schedule in cron format in app timezone, e.g. "0 9 * * 1" for monday 9:00
+	Schedule string
+	// Period in seconds covered by report, e.g. 86400 for daily digest
+	Period time.Duration
+	// Notifiers names
+	Notifiers []string
+	// URLs (links or names) and Tags covered by digest, all urls if both are empty
+	URLs []string
+	Tags []string
+	// Slowest is number of slowest monitors in report, 5 by default
+	Slowest  int
+	schedule *schedule
+	lastRun  time.Time
+}
+
+func (d *DigestConfig) validate() (err error) {
+	if d.Name == "" {
+		return errors.New("digest requires name")
+	}
+	if d.Period <= 0 {
+		return errors.New("digest period should be positive")
+	}
+	if len(d.Notifiers) == 0 {
+		return errors.New("digest requires notifiers")
+	}
+	if d.Slowest < 0 {
+		return errors.New("digest slowest should not be negative")
+	}
+	d.schedule, err = parseSchedule(d.Schedule)
+	return err
+}
+
+// MonitorStats describes url checks during digest period
+type MonitorStats struct {
+	URL URL `json:"url"`
+	// Uptime in percents
+	Uptime    float64       `json:"uptime"`
+	Incidents int           `json:"incidents"`
+	Downtime  time.Duration `json:"downtime"`
+	Checks    int           `json:"checks"`
+	// AvgResponse & MaxResponse are response times of checks
+	AvgResponse    time.Duration `json:"avg_response"`
+	MaxResponse    time.Duration `json:"max_response"`
+	ContentChanges int           `json:"content_changes"`
+	// NoData is set for monitors without checks in period, their uptime isn't known
+	NoData bool `json:"no_data"`
+}
+
+// Digest is summary report of monitors for period
+type Digest struct {
+	Name string    `json:"name"`
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	// Uptime is average uptime of monitors with checks in percents
+	Uptime float64 `json:"uptime"`
+	// NoData is set if none of monitors has checks in period
+	NoData         bool           `json:"no_data"`
+	Incidents      int            `json:"incidents"`
+	Downtime       time.Duration  `json:"downtime"`
+	ContentChanges int            `json:"content_changes"`
+	Monitors       []MonitorStats `json:"monitors"`
+	// Slowest monitors ordered by average response time
+	Slowest []MonitorStats `json:"slowest"`
+}
+
+// DigestNotifier could be implemented by notifiers to receive digests
+type DigestNotifier interface {
+	NotifyDigest(Digest)
+}
+
+func (w *Watcher) initDigests(digests []DigestConfig) {
+	names := make(map[string]bool)
+	for idx := range digests {
+		d := digests[idx]
+		if err := d.validate(); err != nil {
+			log.Fatal().Err(err).Str("digest", d.Name).Msg("Invalid digest")
+		}
+		if names[d.Name] {
+			log.Fatal().Str("digest", d.Name).Msg("Duplicate digest name")
+		}
+		names[d.Name] = true
+		w.digests = append(w.digests, &d)
+	}
+}
+
+// recordCheck stores response time, content changes & incidents of url for digests,
+// times are stored in UTC, so they could be compared in queries
+func (w *Watcher) recordCheck(update URLUpdate) {
+	url := update.New
+	changes := 0
+	if update.Old.Good() && url.Good() {
+		for _, change := range update.Changed {
+			if change == HashChange {
+				changes++
+			}
+		}
+	}
+	_, err := w.db.Exec(
+		"INSERT INTO stats (link, hour, checks, response_total, response_max, content_changes) "+
+			"VALUES(?, ?, 1, ?, ?, ?) ON CONFLICT(link, hour) DO UPDATE SET checks=checks+1, "+
+			"response_total=response_total+excluded.response_total, "+
+			"response_max=MAX(response_max, excluded.response_max), "+
+			"content_changes=content_changes+excluded.content_changes;",
+		url.Link, update.Created.UTC().Truncate(time.Hour), int64(url.ResponseTime), int64(url.ResponseTime), changes)
+	if err != nil {
+		url.log(log.Error).Err(err).Msg("Failed to save check stats")
+	}
+	switch {
+	// incident is also started for urls which were down before first recorded check
+	case !url.Good():
+		_, err = w.db.Exec("INSERT INTO incidents (link, started) SELECT ?, ? "+
+			"WHERE NOT EXISTS (SELECT 1 FROM incidents WHERE link=? AND ended IS NULL)",
+			url.Link, url.DownSince.UTC(), url.Link)
+	case !update.Old.Good() && url.Good():
+		_, err = w.db.Exec("UPDATE incidents SET ended=? WHERE link=? AND ended IS NULL", update.Created.UTC(), url.Link)
+	}
+	if err != nil {
+		url.log(log.Error).Err(err).Msg("Failed to save incident")
+	}
+}
+
+// GetDigest returns report of urls covered by digest for period before to
+func (w *Watcher) GetDigest(cfg DigestConfig, to time.Time) (Digest, error) {
+	from := to.Add(-cfg.Period * time.Second)
+	digest := Digest{
+		Name:     cfg.Name,
+		From:     from,
+		To:       to,
+		Monitors: make([]MonitorStats, 0),
+		Slowest:  make([]MonitorStats, 0),
+	}
+	stats := make(map[string]*MonitorStats)
+	for _, url := range w.GetUrls() {
+		if coversURL(cfg.URLs, cfg.Tags, url) {
+			digest.Monitors = append(digest.Monitors, MonitorStats{URL: url})
+		}
+	}
+	for idx := range digest.Monitors {
+		stats[digest.Monitors[idx].URL.Link] = &digest.Monitors[idx]
+	}
+	rows, err := w.db.Query(
+		"SELECT link, SUM(checks), SUM(response_total), MAX(response_max), SUM(content_changes) FROM stats "+
+			"WHERE hour>=? AND hour<? GROUP BY link;",
+		from.UTC().Truncate(time.Hour), to.UTC())
+	if err != nil {
+		return digest, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			link          string
+			checks        int
+			total, maxRes int64
+			changes       int
+		)
+		if err := rows.Scan(&link, &checks, &total, &maxRes, &changes); err != nil {
+			return digest, err
+		}
+		s, ok := stats[link]
+		if !ok || checks == 0 {
+			continue
+		}
+		s.Checks, s.ContentChanges = checks, changes
+		s.AvgResponse, s.MaxResponse = time.Duration(total/int64(checks)), time.Duration(maxRes)
+	}
+	if err := rows.Err(); err != nil {
+		return digest, err
+	}
+	rows, err = w.db.Query("SELECT link, started, ended FROM incidents WHERE started<? AND (ended IS NULL OR ended>?);",
+		to.UTC(), from.UTC())
+	if err != nil {
+		return digest, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			link    string
+			started time.Time
+			ended   sql.NullTime
+		)
+		if err := rows.Scan(&link, &started, &ended); err != nil {
+			return digest, err
+		}
+		s, ok := stats[link]
+		if !ok {
+			continue
+		}
+		end := to
+		if ended.Valid && ended.Time.Before(to) {
+			end = ended.Time
+		}
+		if started.Before(from) {
+			started = from
+		}
+		s.Incidents++
+		s.Downtime += end.Sub(started)
+	}
+	if err := rows.Err(); err != nil {
+		return digest, err
+	}
+	measured := 0
+	for idx := range digest.Monitors {
+		s := &digest.Monitors[idx]
+		digest.Incidents += s.Incidents
+		digest.Downtime += s.Downtime
+		digest.ContentChanges += s.ContentChanges
+		if s.Checks == 0 {
+			s.NoData = true
+			continue
+		}
+		s.Uptime = 100 * (1 - float64(s.Downtime)/float64(to.Sub(from)))
+		digest.Uptime += s.Uptime
+		measured++
+		digest.Slowest = append(digest.Slowest, *s)
+	}
+	if measured != 0 {
+		digest.Uptime /= float64(measured)
+	}
+	digest.NoData = measured == 0
+	sort.SliceStable(digest.Slowest, func(i, j int) bool {
+		return digest.Slowest[i].AvgResponse > digest.Slowest[j].AvgResponse
+	})
+	slowest := cfg.Slowest
+	if slowest == 0 {
+		slowest = defaultSlowest
+	}
+	if len(digest.Slowest) > slowest {
+		digest.Slowest = digest.Slowest[:slowest]
+	}
+	return digest, nil
+}
+
+// GetDigestConfig returns configured digest by name
+func (w *Watcher) GetDigestConfig(name string) (DigestConfig, bool) {
+	for _, d := range w.digests {
+		if d.Name == name {
+			return *d, true
+		}
+	}
+	return DigestConfig{}, false
+}
+
+// checkDigests sends digests which schedule matches current minute
+func (w *Watcher) checkDigests(notifiers []Notifier) {
+	now := time.Now().In(w.locale.Location).Truncate(time.Minute)
+	for _, d := range w.digests {
+		if !d.schedule.matches(now) || !d.lastRun.Before(now) {
+			continue
+		}
+		d.lastRun = now
+		digest, err := w.GetDigest(*d, now)
+		if err != nil {
+			log.Error().Err(err).Str("digest", d.Name).Msg("Failed to build digest")
+			continue
+		}
+		log.Info().Str("digest", d.Name).Msg("Sending digest")
+		for _, n := range notifiers {
+			named, ok := n.(Named)
+			if !ok || !containsAny(d.Notifiers, []string{named.Name()}) {
+				continue
+			}
+			if dn, ok := n.(DigestNotifier); ok {
+				go dn.NotifyDigest(digest)
+			}
+		}
+	}
+}
+
+// cleanHistory removes check stats & incidents older than retention
+func (w *Watcher) cleanHistory() {
+	before := time.Now().Add(-w.historyRetention)
+	if _, err := w.db.Exec("DELETE FROM stats WHERE hour<?", before.UTC()); err != nil {
+		log.Error().Err(err).Msg("Failed to clean check stats")
+	}
+	if _, err := w.db.Exec("DELETE FROM incidents WHERE ended<?", before.UTC()); err != nil {
+		log.Error().Err(err).Msg("Failed to clean incidents")
+	}
+}
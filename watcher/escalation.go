@@ -0,0 +1,298 @@
+package watcher
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// EscalationStep notifies notifiers after delay since url went down
+type EscalationStep struct {
+	// Notifiers names
+	Notifiers []string
+	// Delay in seconds since url went down
+	Delay time.Duration
+}
+
+// EscalationPolicy describes ordered steps of incident notifications.
+// Policy applies to URLs (links or names) and urls with any of Tags,
+// all urls if both are empty, first matching policy is used
+type EscalationPolicy struct {
+	Name  string
+	URLs  []string
+	Tags  []string
+	Steps []EscalationStep
+}
+
+func (p *EscalationPolicy) validate() error {
+	if p.Name == "" {
+		return errors.New("escalation policy requires name")
+	}
+	if len(p.Steps) == 0 {
+		return errors.New("escalation policy requires steps")
+	}
+	for idx, step := range p.Steps {
+		if len(step.Notifiers) == 0 {
+			return errors.New("escalation step requires notifiers")
+		}
+		if idx != 0 && step.Delay < p.Steps[idx-1].Delay {
+			return errors.New("escalation steps should be ordered by delay")
+		}
+	}
+	return nil
+}
+
+// reached returns true if notifier is used by any of first steps
+func (p *EscalationPolicy) reached(notifier string, steps int) bool {
+	for _, step := range p.Steps[:steps] {
+		if containsAny(step.Notifiers, []string{notifier}) {
+			return true
+		}
+	}
+	return false
+}
+
+// immediate returns number of steps without delay
+func (p *EscalationPolicy) immediate() int {
+	steps := 0
+	for steps < len(p.Steps) && p.Steps[steps].Delay == 0 {
+		steps++
+	}
+	return steps
+}
+
+// Escalation is active incident of url escalated by policy
+type Escalation struct {
+	URL     string    `json:"url"`
+	Policy  string    `json:"policy"`
+	Started time.Time `json:"started"`
+	// Step is number of notified steps
+	Step   int `json:"step"`
+	policy *EscalationPolicy
+}
+
+// Named could be implemented by notifiers to be used in escalation policies
+type Named interface {
+	Name() string
+}
+
+func (w *Watcher) initEscalations(policies []EscalationPolicy) {
+	names := make(map[string]bool)
+	for idx := range policies {
+		p := policies[idx]
+		if err := p.validate(); err != nil {
+			log.Fatal().Err(err).Str("policy", p.Name).Msg("Invalid escalation policy")
+		}
+		if names[p.Name] {
+			log.Fatal().Str("policy", p.Name).Msg("Duplicate escalation policy name")
+		}
+		names[p.Name] = true
+		w.policies = append(w.policies, &p)
+	}
+	rows, err := w.db.Query("SELECT link, policy, started, step FROM escalations;")
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load escalations")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		e := &Escalation{}
+		if err := rows.Scan(&e.URL, &e.Policy, &e.Started, &e.Step); err != nil {
+			log.Fatal().Err(err).Msg("Failed to load escalation")
+		}
+		for _, p := range w.policies {
+			if p.Name == e.Policy && e.Step <= len(p.Steps) {
+				e.policy = p
+			}
+		}
+		if e.policy == nil {
+			log.Info().Str("url", e.URL).Str("policy", e.Policy).Msg("Escalation policy removed")
+			w.removeEscalation(e)
+			continue
+		}
+		w.escalations[e.URL] = e
+	}
+	if err := rows.Err(); err != nil {
+		log.Fatal().Err(err).Msg("Failed to load escalations")
+	}
+}
+
+// pruneEscalations removes escalations of urls which are not watched anymore
+func (w *Watcher) pruneEscalations() {
+	for link, e := range w.escalations {
+		if w.getURLByLink(link) == nil {
+			log.Info().Str("url", link).Msg("Escalation url removed")
+			delete(w.escalations, link)
+			w.removeEscalation(e)
+		}
+	}
+}
+
+// checkNotifiers validates notifiers used in escalation policies & digests
+func (w *Watcher) checkNotifiers(notifiers []Notifier) {
+	names := make(map[string]bool)
+	digests := make(map[string]bool)
+	for _, n := range notifiers {
+		if named, ok := n.(Named); ok {
+			names[named.Name()] = true
+			_, digests[named.Name()] = n.(DigestNotifier)
+		}
+	}
+	for _, d := range w.digests {
+		for _, name := range d.Notifiers {
+			if !digests[name] {
+				log.Fatal().Str("digest", d.Name).Str("notifier", name).Msg("Unknown notifier in digest or digests are not supported")
+			}
+		}
+	}
+	for _, p := range w.policies {
+		for _, step := range p.Steps {
+			for _, name := range step.Notifiers {
+				if !names[name] {
+					log.Fatal().Str("policy", p.Name).Str("notifier", name).Msg("Unknown notifier in escalation policy")
+				}
+			}
+		}
+	}
+}
+
+// policy returns escalation policy of url
+func (w *Watcher) policy(u URL) *EscalationPolicy {
+	for _, p := range w.policies {
+		if coversURL(p.URLs, p.Tags, u) {
+			return p
+		}
+	}
+	return nil
+}
+
+// escalationFilter starts & finishes escalations of url incidents,
+// returned filter allows notifiers which are not used by url policy
+// or which steps are reached
+func (w *Watcher) escalationFilter(update URLUpdate) func(Notifier) bool {
+	p := w.policy(update.New)
+	if p == nil {
+		return func(Notifier) bool { return true }
+	}
+	w.escalationMux.Lock()
+	e, ok := w.escalations[update.New.Link]
+	if !ok && !update.New.Good() {
+		started := update.New.DownSince
+		if started.IsZero() {
+			started = update.Created
+		}
+		e = &Escalation{URL: update.New.Link, Policy: p.Name, Started: started, policy: p}
+		e.Step = w.dueSteps(e, update.Created)
+		w.escalations[e.URL] = e
+		w.saveEscalation(e)
+		ok = true
+		log.Info().Str("url", e.URL).Str("policy", p.Name).Int("step", e.Step).Msg("Escalation started")
+	}
+	steps := p.immediate()
+	if ok {
+		p, steps = e.policy, e.Step
+		if update.New.Good() {
+			delete(w.escalations, e.URL)
+			w.removeEscalation(e)
+			log.Info().Str("url", e.URL).Msg("Escalation finished")
+		}
+	}
+	w.escalationMux.Unlock()
+	return func(n Notifier) bool {
+		named, ok := n.(Named)
+		if !ok {
+			return true
+		}
+		for _, step := range p.Steps {
+			if containsAny(step.Notifiers, []string{named.Name()}) {
+				return p.reached(named.Name(), steps)
+			}
+		}
+		return true
+	}
+}
+
+// dueSteps returns number of escalation steps which delay is over
+func (w *Watcher) dueSteps(e *Escalation, now time.Time) int {
+	steps := e.Step
+	for steps < len(e.policy.Steps) && !now.Before(e.Started.Add(e.policy.Steps[steps].Delay*time.Second)) {
+		steps++
+	}
+	return steps
+}
+
+// checkEscalations notifies next steps of escalations which delay is over,
+// acknowledged urls, urls silenced by maintenance or unreachable due to parent are not escalated
+func (w *Watcher) checkEscalations(notifiers []Notifier) {
+	now := time.Now()
+	w.escalationMux.Lock()
+	defer w.escalationMux.Unlock()
+	for link, e := range w.escalations {
+		url := w.getURLByLink(link)
+		// escalation is finished by recovery update, so reached steps receive it
+		if url == nil || url.Good() {
+			continue
+		}
+		steps := w.dueSteps(e, now)
+		if steps == e.Step || url.AckedBy != "" || url.UnreachableVia != "" || w.inMaintenance(*url, now) != nil {
+			continue
+		}
+		// notifiers of escalation step haven't seen incident,
+		// so it's reported as url went down
+		old := *url
+		old.Status, old.Err, old.DownSince = http.StatusOK, "", time.Time{}
+		for ; e.Step < steps; e.Step++ {
+			names := e.policy.Steps[e.Step].Notifiers
+			update := URLUpdate{
+				New:        *url,
+				Old:        old,
+				Changed:    []int{StatusChange},
+				Created:    now,
+				Dependents: w.dependents(url.id),
+				Escalation: e.Step + 1,
+			}
+			log.Info().Str("url", link).Str("policy", e.Policy).Int("step", e.Step+1).Msg("Incident escalated")
+			for _, n := range notifiers {
+				named, ok := n.(Named)
+				if !ok || !containsAny(names, []string{named.Name()}) || e.policy.reached(named.Name(), e.Step) {
+					continue
+				}
+				if m, ok := n.(Matcher); ok && !m.Match(update) {
+					continue
+				}
+				go n.Notify(update)
+			}
+		}
+		w.saveEscalation(e)
+	}
+}
+
+// GetEscalations returns active escalations ordered by start
+func (w *Watcher) GetEscalations() []Escalation {
+	w.escalationMux.Lock()
+	defer w.escalationMux.Unlock()
+	res := make([]Escalation, 0, len(w.escalations))
+	for _, e := range w.escalations {
+		res = append(res, *e)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Started.Before(res[j].Started)
+	})
+	return res
+}
+
+func (w *Watcher) saveEscalation(e *Escalation) {
+	_, err := w.db.Exec("INSERT OR REPLACE INTO escalations (link, policy, started, step) VALUES(?, ?, ?, ?)",
+		e.URL, e.Policy, e.Started, e.Step)
+	if err != nil {
+		log.Error().Err(err).Str("url", e.URL).Msg("Failed to save escalation")
+	}
+}
+
+func (w *Watcher) removeEscalation(e *Escalation) {
+	if _, err := w.db.Exec("DELETE FROM escalations WHERE link=?", e.URL); err != nil {
+		log.Error().Err(err).Str("url", e.URL).Msg("Failed to remove escalation")
+	}
+}
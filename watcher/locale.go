@@ -0,0 +1,281 @@
+package watcher
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultLanguage is used for missing translations
+const DefaultLanguage = "en"
+
+// catalogs contains messages of notifications & web ui by language,
+// messages are fmt format strings
+var catalogs = map[string]map[string]string{
+	"en": {
+		"ok":                "OK",
+		"status":            "%d status",
+		"dependents":        "%d dependent urls",
+		"down_for":          "down for %s",
+		"still_down":        "still down for %s",
+		"recovered_after":   "recovered after %s",
+		"unreachable":       "unreachable due to %s",
+		"dashboard":         "Dashboard",
+		"duration_day":      "%dd",
+		"duration_hour":     "%dh",
+		"duration_minute":   "%dm",
+		"duration_second":   "%ds",
+		"all_tags":          "All tags",
+		"url":               "Url",
+		"tags":              "Tags",
+		"last_change":       "Last change",
+		"status_column":     "Status",
+		"ungrouped":         "Ungrouped",
+		"group_health":      "%d/%d up",
+		"notifications":     "Notifications",
+		"notifier":          "Notifier",
+		"healthy":           "healthy",
+		"failing":           "failing",
+		"attempts":          "Attempts",
+		"failures":          "Failures",
+		"last_success":      "Last success",
+		"last_failure":      "Last failure",
+		"error":             "Error",
+		"deliveries":        "Deliveries",
+		"time":              "Time",
+		"target":            "Target",
+		"response_code":     "Response",
+		"latency":           "Latency",
+		"ack":               "Acknowledge",
+		"acked_by":          "acked by %s",
+		"ack_prompt":        "Your name",
+		"ack_confirm":       "Acknowledge incident of %s?",
+		"ack_done":          "%s acknowledged by %s",
+		"ack_unknown":       "Unknown url %s",
+		"ack_no_incident":   "%s is not down",
+		"ack_invalid":       "Invalid or expired acknowledgement link",
+		"ack_usage":         "Usage: /ack <name or url>",
+		"digest":            "%s digest",
+		"uptime":            "uptime %s",
+		"no_data":           "no data",
+		"incidents":         "incidents: %d",
+		"downtime":          "downtime %s",
+		"content_changes":   "content changes: %d",
+		"slowest":           "Slowest",
+		"uptime_column":     "Uptime",
+		"incident_column":   "Incidents",
+		"downtime_column":   "Downtime",
+		"changes_column":    "Content changes",
+		"avg_response":      "Avg response",
+		"more":              "+%d more",
+		"status_code":       "Status code",
+		"response_time":     "Response time",
+		"dependents_column": "Dependent urls",
+	},
+	"de": {
+		"ok":                "OK",
+		"status":            "Status %d",
+		"dependents":        "%d abhängige URLs",
+		"down_for":          "seit %s ausgefallen",
+		"still_down":        "weiterhin seit %s ausgefallen",
+		"recovered_after":   "wiederhergestellt nach %s",
+		"unreachable":       "nicht erreichbar wegen %s",
+		"dashboard":         "Dashboard",
+		"duration_day":      "%dT",
+		"duration_hour":     "%dStd",
+		"duration_minute":   "%dMin",
+		"duration_second":   "%ds",
+		"all_tags":          "Alle Tags",
+		"url":               "URL",
+		"tags":              "Tags",
+		"last_change":       "Letzte Änderung",
+		"status_column":     "Status",
+		"ungrouped":         "Ohne Gruppe",
+		"group_health":      "%d/%d verfügbar",
+		"notifications":     "Benachrichtigungen",
+		"notifier":          "Kanal",
+		"healthy":           "funktioniert",
+		"failing":           "fehlerhaft",
+		"attempts":          "Versuche",
+		"failures":          "Fehler",
+		"last_success":      "Letzter Erfolg",
+		"last_failure":      "Letzter Fehler",
+		"error":             "Fehler",
+		"deliveries":        "Zustellungen",
+		"time":              "Zeit",
+		"target":            "Ziel",
+		"response_code":     "Antwort",
+		"latency":           "Latenz",
+		"ack":               "Bestätigen",
+		"acked_by":          "bestätigt von %s",
+		"ack_prompt":        "Ihr Name",
+		"ack_confirm":       "Störung von %s bestätigen?",
+		"ack_done":          "%s bestätigt von %s",
+		"ack_unknown":       "Unbekannte URL %s",
+		"ack_no_incident":   "%s ist nicht ausgefallen",
+		"ack_invalid":       "Ungültiger oder abgelaufener Bestätigungslink",
+		"ack_usage":         "Verwendung: /ack <Name oder URL>",
+		"digest":            "Bericht %s",
+		"uptime":            "Verfügbarkeit %s",
+		"no_data":           "keine Daten",
+		"incidents":         "Störungen: %d",
+		"downtime":          "Ausfallzeit %s",
+		"content_changes":   "Inhaltsänderungen: %d",
+		"slowest":           "Am langsamsten",
+		"uptime_column":     "Verfügbarkeit",
+		"incident_column":   "Störungen",
+		"downtime_column":   "Ausfallzeit",
+		"changes_column":    "Inhaltsänderungen",
+		"avg_response":      "Ø Antwortzeit",
+		"more":              "+%d weitere",
+		"status_code":       "Statuscode",
+		"response_time":     "Antwortzeit",
+		"dependents_column": "Abhängige URLs",
+	},
+	"ru": {
+		"ok":                "OK",
+		"status":            "статус %d",
+		"dependents":        "зависимых адресов: %d",
+		"down_for":          "недоступен %s",
+		"still_down":        "всё ещё недоступен %s",
+		"recovered_after":   "восстановлен через %s",
+		"unreachable":       "недоступен из-за %s",
+		"dashboard":         "Панель",
+		"duration_day":      "%dд",
+		"duration_hour":     "%dч",
+		"duration_minute":   "%dм",
+		"duration_second":   "%dс",
+		"all_tags":          "Все теги",
+		"url":               "Адрес",
+		"tags":              "Теги",
+		"last_change":       "Последнее изменение",
+		"status_column":     "Статус",
+		"ungrouped":         "Без группы",
+		"group_health":      "%d/%d доступны",
+		"notifications":     "Уведомления",
+		"notifier":          "Канал",
+		"healthy":           "работает",
+		"failing":           "сбоит",
+		"attempts":          "Попыток",
+		"failures":          "Ошибок",
+		"last_success":      "Последний успех",
+		"last_failure":      "Последняя ошибка",
+		"error":             "Ошибка",
+		"deliveries":        "Доставки",
+		"time":              "Время",
+		"target":            "Получатель",
+		"response_code":     "Ответ",
+		"latency":           "Задержка",
+		"ack":               "Подтвердить",
+		"acked_by":          "подтвердил %s",
+		"ack_prompt":        "Ваше имя",
+		"ack_confirm":       "Подтвердить инцидент %s?",
+		"ack_done":          "инцидент %s подтвердил %s",
+		"ack_unknown":       "Неизвестный адрес %s",
+		"ack_no_incident":   "%s доступен",
+		"ack_invalid":       "Неверная или устаревшая ссылка подтверждения",
+		"ack_usage":         "Использование: /ack <имя или адрес>",
+		"digest":            "Сводка %s",
+		"uptime":            "доступность %s",
+		"no_data":           "нет данных",
+		"incidents":         "инцидентов: %d",
+		"downtime":          "простой %s",
+		"content_changes":   "изменений контента: %d",
+		"slowest":           "Самые медленные",
+		"uptime_column":     "Доступность",
+		"incident_column":   "Инциденты",
+		"downtime_column":   "Простой",
+		"changes_column":    "Изменения контента",
+		"avg_response":      "Среднее время ответа",
+		"more":              "+%d ещё",
+		"status_code":       "Код ответа",
+		"response_time":     "Время ответа",
+		"dependents_column": "Зависимые адреса",
+	},
+}
+
+// Locale describes language & timezone used to render messages
+type Locale struct {
+	Language string
+	Location *time.Location
+}
+
+// NewLocale validates language & loads timezone
+func NewLocale(language, timezone string) (Locale, error) {
+	if _, ok := catalogs[language]; !ok {
+		return Locale{}, errors.New("unknown language " + language)
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return Locale{}, err
+	}
+	return Locale{Language: language, Location: location}, nil
+}
+
+// T returns translated message formatted with args,
+// english message is used if translation is missing
+func (l Locale) T(key string, args ...interface{}) string {
+	message, ok := catalogs[l.Language][key]
+	if !ok {
+		if message, ok = catalogs[DefaultLanguage][key]; !ok {
+			message = key
+		}
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// Messages returns whole catalog of locale language
+func (l Locale) Messages() map[string]string {
+	messages := make(map[string]string, len(catalogs[DefaultLanguage]))
+	for key, message := range catalogs[DefaultLanguage] {
+		messages[key] = message
+	}
+	for key, message := range catalogs[l.Language] {
+		messages[key] = message
+	}
+	return messages
+}
+
+// Time formats time in locale timezone
+func (l Locale) Time(t time.Time) string {
+	location := l.Location
+	if location == nil {
+		location = time.UTC
+	}
+	return t.In(location).Format("2006-01-02 15:04:05 MST")
+}
+
+// Duration formats duration using two largest units, e.g. "1d 3h" or "12m"
+func (l Locale) Duration(d time.Duration) string {
+	if d < time.Second {
+		return l.T("duration_second", 0)
+	}
+	units := []struct {
+		key  string
+		size time.Duration
+	}{
+		{"duration_day", 24 * time.Hour},
+		{"duration_hour", time.Hour},
+		{"duration_minute", time.Minute},
+		{"duration_second", time.Second},
+	}
+	var parts []string
+	for _, unit := range units {
+		if d < unit.size {
+			if len(parts) != 0 {
+				break
+			}
+			continue
+		}
+		parts = append(parts, l.T(unit.key, int(d/unit.size)))
+		d %= unit.size
+		if len(parts) == 2 || d < time.Second {
+			break
+		}
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,237 @@
+package watcher
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Maintenance describes window when checks are performed but notifications are silenced
+type Maintenance struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	// Start of one-off window
+	Start time.Time `json:"start"`
+	// Schedule of recurring window in cron format
+	Schedule string `json:"schedule"`
+	// Duration of window in seconds
+	Duration time.Duration `json:"duration"`
+	// URLs and Tags affected by window, all urls if both are empty
+	URLs []string `json:"urls"`
+	Tags []string `json:"tags"`
+	// AlertAfter sends notification if url is still down after window ended
+	AlertAfter bool `json:"alert_after"`
+	FromConfig bool `json:"from_config"`
+	schedule   *schedule
+}
+
+func (m *Maintenance) validate() (err error) {
+	if m.Duration <= 0 {
+		return errors.New("maintenance duration should be positive")
+	}
+	if m.Schedule != "" {
+		if m.schedule, err = parseSchedule(m.Schedule); err != nil {
+			return err
+		}
+	} else if m.Start.IsZero() {
+		return errors.New("maintenance requires start or schedule")
+	}
+	return nil
+}
+
+func (m *Maintenance) covers(u URL) bool {
+	return coversURL(m.URLs, m.Tags, u)
+}
+
+// coversURL checks if url is in urls (links or names) or has any of tags,
+// all urls are covered if both are empty
+func coversURL(urls, tags []string, u URL) bool {
+	if len(urls) == 0 && len(tags) == 0 {
+		return true
+	}
+	for _, link := range urls {
+		if link == u.Link || link == u.Name {
+			return true
+		}
+	}
+	for _, tag := range tags {
+		if u.HasTag(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Maintenance) active(t time.Time) bool {
+	duration := m.Duration * time.Second
+	if m.schedule == nil {
+		return !t.Before(m.Start) && t.Before(m.Start.Add(duration))
+	}
+	from := t.Add(-duration)
+	for start := t.Truncate(time.Minute); start.After(from); start = start.Add(-time.Minute) {
+		if m.schedule.matches(start) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Maintenance) save(db *sql.DB) error {
+	urls, err := json.Marshal(m.URLs)
+	if err != nil {
+		return err
+	}
+	tags, err := json.Marshal(m.Tags)
+	if err != nil {
+		return err
+	}
+	res, err := db.Exec(
+		"INSERT INTO maintenance (name, start, schedule, duration, urls, tags, alert_after) VALUES(?, ?, ?, ?, ?, ?, ?)",
+		m.Name, m.Start, m.Schedule, int64(m.Duration), string(urls), string(tags), m.AlertAfter)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	m.ID = int(id)
+	return err
+}
+
+// silence stores url state before it was silenced by maintenance
+type silence struct {
+	url    *URL
+	old    URL
+	window *Maintenance
+}
+
+func (w *Watcher) initMaintenance(windows []Maintenance) {
+	for idx := range windows {
+		m := windows[idx]
+		// config windows are not stored in db and use negative ids
+		m.ID = -(idx + 1)
+		m.FromConfig = true
+		if err := m.validate(); err != nil {
+			log.Fatal().Err(err).Str("name", m.Name).Msg("Invalid maintenance window")
+		}
+		w.maintenance = append(w.maintenance, &m)
+	}
+	rows, err := w.db.Query("SELECT id, name, start, schedule, duration, urls, tags, alert_after FROM maintenance;")
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to get maintenance windows from DB")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			m        Maintenance
+			duration int64
+			urls     string
+			tags     string
+		)
+		err := rows.Scan(&m.ID, &m.Name, &m.Start, &m.Schedule, &duration, &urls, &tags, &m.AlertAfter)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to read maintenance window")
+		}
+		m.Duration = time.Duration(duration)
+		if err := json.Unmarshal([]byte(urls), &m.URLs); err != nil {
+			log.Error().Err(err).Int("id", m.ID).Msg("Invalid maintenance window urls")
+		}
+		if err := json.Unmarshal([]byte(tags), &m.Tags); err != nil {
+			log.Error().Err(err).Int("id", m.ID).Msg("Invalid maintenance window tags")
+		}
+		if err := m.validate(); err != nil {
+			log.Error().Err(err).Int("id", m.ID).Msg("Invalid maintenance window")
+			continue
+		}
+		w.maintenance = append(w.maintenance, &m)
+	}
+}
+
+// inMaintenance returns active maintenance window for url,
+// schedules are matched in app timezone
+func (w *Watcher) inMaintenance(u URL, t time.Time) *Maintenance {
+	t = t.In(w.locale.Location)
+	w.maintenanceMux.RLock()
+	defer w.maintenanceMux.RUnlock()
+	for _, m := range w.maintenance {
+		if m.covers(u) && m.active(t) {
+			return m
+		}
+	}
+	return nil
+}
+
+// InMaintenance checks if notifications of url are silenced now
+func (w *Watcher) InMaintenance(u URL) bool {
+	return w.inMaintenance(u, time.Now()) != nil
+}
+
+// checkMaintenanceEnd sends delayed notifications for urls which windows are over
+func (w *Watcher) checkMaintenanceEnd(silenced map[int]silence, notifiers []Notifier) {
+	now := time.Now()
+	for id, s := range silenced {
+		if w.inMaintenance(*s.url, now) != nil {
+			continue
+		}
+		delete(silenced, id)
+		s.url.log(log.Info).Msg("Maintenance ended")
+		// recovery is always sent, failure only if window asks for it
+		recovered := !s.old.Good() && s.url.Good()
+		if recovered || (s.window.AlertAfter && !s.url.Good()) {
+			w.notify(notifiers, URLUpdate{
+				New:     *s.url,
+				Old:     s.old,
+				Changed: []int{StatusChange},
+				Created: now,
+			})
+		}
+	}
+}
+
+// GetMaintenance returns all maintenance windows
+func (w *Watcher) GetMaintenance() []Maintenance {
+	w.maintenanceMux.RLock()
+	defer w.maintenanceMux.RUnlock()
+	res := make([]Maintenance, 0, len(w.maintenance))
+	for _, m := range w.maintenance {
+		res = append(res, *m)
+	}
+	return res
+}
+
+// AddMaintenance validates & stores new maintenance window
+func (w *Watcher) AddMaintenance(m Maintenance) (Maintenance, error) {
+	m.FromConfig = false
+	if err := m.validate(); err != nil {
+		return m, err
+	}
+	if err := m.save(w.db); err != nil {
+		log.Error().Err(err).Msg("Failed to save maintenance window")
+		return m, err
+	}
+	w.maintenanceMux.Lock()
+	w.maintenance = append(w.maintenance, &m)
+	w.maintenanceMux.Unlock()
+	return m, nil
+}
+
+// RemoveMaintenance deletes maintenance window
+func (w *Watcher) RemoveMaintenance(id int) error {
+	w.maintenanceMux.Lock()
+	defer w.maintenanceMux.Unlock()
+	for idx, m := range w.maintenance {
+		if m.ID != id {
+			continue
+		}
+		if m.FromConfig {
+			return errors.New("maintenance window from config could not be removed")
+		}
+		if _, err := w.db.Exec("DELETE FROM maintenance WHERE id=?", id); err != nil {
+			return err
+		}
+		w.maintenance = append(w.maintenance[:idx], w.maintenance[idx+1:]...)
+		return nil
+	}
+	return errors.New("maintenance window not found")
+}
@@ -0,0 +1,175 @@
+package watcher
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Outbox message statuses
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	OutboxDead    = "dead"
+)
+
+// OutboxConfig describes retries of failed notifications
+type OutboxConfig struct {
+	// MaxAttempts before message is marked as dead letter
+	MaxAttempts int `default:"10"`
+	// RetryDelay in seconds, doubled after each attempt up to MaxRetryDelay
+	RetryDelay    time.Duration `default:"10"`
+	MaxRetryDelay time.Duration `default:"3600"`
+	// Retention of sent & dead messages & delivery log in seconds
+	Retention time.Duration `default:"604800"`
+	// UnhealthyAfter number of failed deliveries in a row notifier is reported as failing
+	UnhealthyAfter int `default:"3"`
+}
+
+// outboxLease is time message is reserved for delivery attempt,
+// message is retried after it if attempt result wasn't stored, e.g. on crash
+const outboxLease = 5 * time.Minute
+
+// OutboxMessage is batch of updates waiting for delivery by notifier
+type OutboxMessage struct {
+	ID       int64  `json:"id"`
+	Notifier string `json:"notifier"`
+	// Target is recipient of notifiers tracking delivery per recipient
+	Target      string      `json:"target"`
+	Updates     []URLUpdate `json:"updates"`
+	Status      string      `json:"status"`
+	Attempts    int         `json:"attempts"`
+	LastError   string      `json:"last_error"`
+	Created     time.Time   `json:"created"`
+	NextAttempt time.Time   `json:"next_attempt"`
+}
+
+const outboxColumns = "id, notifier, target, updates, status, attempts, last_error, created, next_attempt"
+
+func scanOutboxMessages(rows *sql.Rows) ([]OutboxMessage, error) {
+	defer rows.Close()
+	messages := make([]OutboxMessage, 0)
+	for rows.Next() {
+		var (
+			m       OutboxMessage
+			updates string
+		)
+		err := rows.Scan(&m.ID, &m.Notifier, &m.Target, &updates, &m.Status, &m.Attempts, &m.LastError, &m.Created, &m.NextAttempt)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(updates), &m.Updates); err != nil {
+			log.Error().Err(err).Int64("id", m.ID).Msg("Invalid outbox message updates")
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+// EnqueueMessage stores updates for delivery by notifier to target,
+// stored message is due immediately & should be claimed before delivery attempt
+func (w *Watcher) EnqueueMessage(notifier, target string, updates []URLUpdate) (OutboxMessage, error) {
+	now := time.Now()
+	m := OutboxMessage{
+		Notifier:    notifier,
+		Target:      target,
+		Updates:     updates,
+		Status:      OutboxPending,
+		Created:     now,
+		NextAttempt: now,
+	}
+	data, err := json.Marshal(updates)
+	if err != nil {
+		return m, err
+	}
+	res, err := w.db.Exec(
+		"INSERT INTO outbox (notifier, target, updates, status, attempts, last_error, created, next_attempt) "+
+			"VALUES(?, ?, ?, ?, 0, '', ?, ?)",
+		notifier, target, string(data), OutboxPending, m.Created, m.NextAttempt)
+	if err != nil {
+		return m, err
+	}
+	m.ID, err = res.LastInsertId()
+	return m, err
+}
+
+// ClaimMessage reserves due message for delivery attempt,
+// false is returned if message is already reserved or delivered
+func (w *Watcher) ClaimMessage(m OutboxMessage) bool {
+	now := time.Now()
+	res, err := w.db.Exec("UPDATE outbox SET next_attempt=? WHERE id=? AND status=? AND next_attempt<=?",
+		now.Add(outboxLease), m.ID, OutboxPending, now)
+	if err != nil {
+		log.Error().Err(err).Int64("id", m.ID).Msg("Failed to claim outbox message")
+		return false
+	}
+	count, err := res.RowsAffected()
+	return err == nil && count == 1
+}
+
+// PendingMessages returns undelivered messages of notifier in order of creation,
+// including ones waiting for retry
+func (w *Watcher) PendingMessages(notifier string) ([]OutboxMessage, error) {
+	rows, err := w.db.Query(
+		"SELECT "+outboxColumns+" FROM outbox WHERE notifier=? AND status=? ORDER BY id;",
+		notifier, OutboxPending)
+	if err != nil {
+		return nil, err
+	}
+	return scanOutboxMessages(rows)
+}
+
+// MessageDelivered marks message as sent
+func (w *Watcher) MessageDelivered(m OutboxMessage) {
+	_, err := w.db.Exec("UPDATE outbox SET status=?, attempts=?, last_error='' WHERE id=?",
+		OutboxSent, m.Attempts+1, m.ID)
+	if err != nil {
+		log.Error().Err(err).Int64("id", m.ID).Msg("Failed to update outbox message")
+	}
+}
+
+// MessageFailed schedules retry of message with exponential backoff,
+// message is marked as dead letter after max attempts
+func (w *Watcher) MessageFailed(m OutboxMessage, sendErr error) {
+	m.Attempts++
+	m.Status = OutboxPending
+	delay := w.outbox.RetryDelay * time.Second
+	for idx := 1; idx < m.Attempts && delay < w.outbox.MaxRetryDelay*time.Second; idx++ {
+		delay *= 2
+	}
+	if delay > w.outbox.MaxRetryDelay*time.Second {
+		delay = w.outbox.MaxRetryDelay * time.Second
+	}
+	if m.Attempts >= w.outbox.MaxAttempts {
+		m.Status = OutboxDead
+		log.Error().Err(sendErr).Int64("id", m.ID).Str("notifier", m.Notifier).Msg("Message moved to dead letters")
+	}
+	_, err := w.db.Exec("UPDATE outbox SET status=?, attempts=?, last_error=?, next_attempt=? WHERE id=?",
+		m.Status, m.Attempts, sendErr.Error(), time.Now().Add(delay), m.ID)
+	if err != nil {
+		log.Error().Err(err).Int64("id", m.ID).Msg("Failed to update outbox message")
+	}
+}
+
+// GetOutbox returns last messages filtered by notifier & status, empty filters match all
+func (w *Watcher) GetOutbox(notifier, status string, limit int) ([]OutboxMessage, error) {
+	rows, err := w.db.Query(
+		"SELECT "+outboxColumns+" FROM outbox WHERE (?='' OR notifier=?) AND (?='' OR status=?) "+
+			"ORDER BY id DESC LIMIT ?;",
+		notifier, notifier, status, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanOutboxMessages(rows)
+}
+
+// cleanOutbox removes delivered & dead messages older than retention
+func (w *Watcher) cleanOutbox() {
+	_, err := w.db.Exec("DELETE FROM outbox WHERE status!=? AND created<?",
+		OutboxPending, time.Now().Add(-w.outbox.Retention*time.Second))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to clean outbox")
+	}
+}
@@ -0,0 +1,129 @@
+package watcher
+
+import (
+	"errors"
+	"regexp"
+)
+
+// Severity levels
+const (
+	SeverityCritical = "critical"
+	SeverityError    = "error"
+	SeverityWarning  = "warning"
+	SeverityInfo     = "info"
+)
+
+var severities = []string{SeverityCritical, SeverityError, SeverityWarning, SeverityInfo}
+
+// changeNames maps change kinds to names used in configuration
+var changeNames = map[string]int{
+	"status":  StatusChange,
+	"content": HashChange,
+	"error":   ErrorChange,
+	"ack":     AckChange,
+}
+
+// Route describes notifier routing rule, empty fields match any update
+type Route struct {
+	// Tags matches urls with any of tags
+	Tags []string
+	// Monitor is regexp matched against url name and link
+	Monitor string
+	// Severities matches urls with any of severities
+	Severities []string
+	// Changes matches updates with any of changes: status, content, error, ack
+	Changes []string
+}
+
+type compiledRoute struct {
+	Route
+	monitor *regexp.Regexp
+	changes []int
+}
+
+func (r compiledRoute) match(update URLUpdate) bool {
+	url := update.New
+	if len(r.Tags) != 0 && !containsAny(url.Tags, r.Tags) {
+		return false
+	}
+	if r.monitor != nil && !r.monitor.MatchString(url.Name) && !r.monitor.MatchString(url.Link) {
+		return false
+	}
+	if len(r.Severities) != 0 && !containsAny([]string{url.Severity}, r.Severities) {
+		return false
+	}
+	if len(r.changes) != 0 {
+		for _, change := range update.Changed {
+			for _, expected := range r.changes {
+				if change == expected {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Router filters updates by routing rules
+type Router struct {
+	routes []compiledRoute
+}
+
+// Match returns true if update matches any of routes, or there are no routes
+func (r *Router) Match(update URLUpdate) bool {
+	if r == nil || len(r.routes) == 0 {
+		return true
+	}
+	for _, route := range r.routes {
+		if route.match(update) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewRouter validates routes & creates router
+func NewRouter(routes []Route) (*Router, error) {
+	router := &Router{}
+	for _, route := range routes {
+		compiled := compiledRoute{Route: route}
+		if route.Monitor != "" {
+			re, err := regexp.Compile(route.Monitor)
+			if err != nil {
+				return nil, err
+			}
+			compiled.monitor = re
+		}
+		for _, severity := range route.Severities {
+			if !containsAny(severities, []string{severity}) {
+				return nil, errors.New("unknown severity " + severity)
+			}
+		}
+		for _, name := range route.Changes {
+			change, ok := changeNames[name]
+			if !ok {
+				return nil, errors.New("unknown change " + name)
+			}
+			compiled.changes = append(compiled.changes, change)
+		}
+		router.routes = append(router.routes, compiled)
+	}
+	return router, nil
+}
+
+// Matcher could be implemented by notifiers to receive only matching updates
+type Matcher interface {
+	Match(URLUpdate) bool
+}
+
+func containsAny(values []string, expected []string) bool {
+	for _, value := range values {
+		for _, e := range expected {
+			if value == e {
+				return true
+			}
+		}
+	}
+	return false
+}
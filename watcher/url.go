@@ -4,9 +4,10 @@ import (
 	"bytes"
 	"crypto/md5"
 	"database/sql"
+	"errors"
 	"io"
 	"net/http"
-	"strconv"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -18,43 +19,74 @@ const (
 	StatusChange = iota
 	HashChange
 	ErrorChange
+	AckChange
 )
 
 // URL struct
 type URL struct {
-	id         int
-	Link       string    `json:"url"`
-	LastChange time.Time `json:"last_change"`
-	Status     int       `json:"status"`
-	Err        string    `json:"error"`
-	lastCheck  time.Time
-	hash       []byte
+	id             int
+	Link           string    `json:"url"`
+	Name           string    `json:"name"`
+	Group          string    `json:"group"`
+	Tags           []string  `json:"tags"`
+	Severity       string    `json:"severity"`
+	Parents        []string  `json:"parents"`
+	LastChange     time.Time `json:"last_change"`
+	Status         int       `json:"status"`
+	Err            string    `json:"error"`
+	UnreachableVia string    `json:"unreachable_via"`
+	// DownSince is time url went down, zero for good urls
+	DownSince time.Time `json:"down_since"`
+	// ResponseTime of last check
+	ResponseTime time.Duration `json:"response_time"`
+	// AckedBy & AckedAt describe acknowledgement of current incident
+	AckedBy   string    `json:"acked_by"`
+	AckedAt   time.Time `json:"acked_at"`
+	lastCheck time.Time
+	hash      []byte
+	parents   []*URL
 }
 
 func (u *URL) log(level func() *zerolog.Event) *zerolog.Event {
 	return level().Str("url", u.Link)
 }
 
-// Update url
-func (u *URL) Update() URLUpdate {
+// checkResult is response of url which is applied to url state by watcher loop
+type checkResult struct {
+	url          *URL
+	hash         []byte
+	status       int
+	responseTime time.Duration
+	err          error
+}
+
+// fetch requests url without changing its state
+func (u *URL) fetch() checkResult {
 	u.log(log.Debug).Msg("Updating")
 	client := &http.Client{Timeout: 5 * time.Second}
+	start := time.Now()
 	resp, err := client.Get(u.Link)
 	if err != nil {
-		return u.change([]byte{}, 0, err)
+		return checkResult{url: u, hash: []byte{}, responseTime: time.Since(start), err: err}
 	}
 	defer resp.Body.Close()
 	hash := md5.New()
 	if _, err := io.Copy(hash, resp.Body); err != nil {
-		return u.change([]byte{}, 0, err)
+		return checkResult{url: u, hash: []byte{}, responseTime: time.Since(start), err: err}
 	}
-	var hashSum []byte = hash.Sum(nil)
-	return u.change(hashSum, resp.StatusCode, nil)
+	return checkResult{url: u, hash: hash.Sum(nil), status: resp.StatusCode, responseTime: time.Since(start)}
 }
 
-func (u *URL) change(hash []byte, status int, err error) URLUpdate {
+// Update url
+func (u *URL) Update() URLUpdate {
+	r := u.fetch()
+	return u.change(r.hash, r.status, r.responseTime, r.err)
+}
+
+func (u *URL) change(hash []byte, status int, responseTime time.Duration, err error) URLUpdate {
 	now := time.Now()
 	old := *u
+	u.ResponseTime = responseTime
 	var changes []int
 	if bytes.Compare(u.hash, hash) != 0 {
 		changes = append(changes, HashChange)
@@ -65,10 +97,28 @@ func (u *URL) change(hash []byte, status int, err error) URLUpdate {
 		u.Err = err.Error()
 		u.Status = 0
 	} else if status != u.Status {
-		changes = append(changes, ErrorChange)
+		changes = append(changes, StatusChange)
 		u.Status = status
 		u.Err = ""
 	}
+	unreachableVia := ""
+	if !u.Good() {
+		if parent := u.downParent(); parent != nil {
+			unreachableVia = parent.Link
+		}
+	}
+	if unreachableVia != u.UnreachableVia {
+		if len(changes) == 0 || changes[len(changes)-1] != StatusChange {
+			changes = append(changes, StatusChange)
+		}
+		u.UnreachableVia = unreachableVia
+	}
+	if u.Good() {
+		u.DownSince = time.Time{}
+		u.AckedBy, u.AckedAt = "", time.Time{}
+	} else if u.DownSince.IsZero() {
+		u.DownSince = now
+	}
 	u.lastCheck = now
 	res := URLUpdate{
 		New:     *u,
@@ -83,13 +133,18 @@ func (u *URL) change(hash []byte, status int, err error) URLUpdate {
 }
 
 func (u *URL) save(db *sql.DB) (err error) {
-	stmt, err := db.Prepare("INSERT OR REPLACE INTO urls VALUES(?, ?, ?, ?, ?)")
+	stmt, err := db.Prepare(
+		"INSERT OR REPLACE INTO urls " +
+			"(link, last_change, hash, status, error, unreachable_via, down_since, acked_by, acked_at) " +
+			"VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)")
 	if err != nil {
 		u.log(log.Error).Err(err).Msg("Failed to prepare save statement")
 		return
 	}
 	defer stmt.Close()
-	res, err := stmt.Exec(u.Link, u.LastChange, u.hash, u.Status, u.Err)
+	downSince := sql.NullTime{Time: u.DownSince, Valid: !u.DownSince.IsZero()}
+	ackedAt := sql.NullTime{Time: u.AckedAt, Valid: !u.AckedAt.IsZero()}
+	res, err := stmt.Exec(u.Link, u.LastChange, u.hash, u.Status, u.Err, u.UnreachableVia, downSince, u.AckedBy, ackedAt)
 	if err != nil {
 		u.log(log.Error).Err(err).Msg("Failed to execute save statement")
 		return
@@ -103,23 +158,72 @@ func (u *URL) save(db *sql.DB) (err error) {
 	return
 }
 
+// HasTag returns true if url is marked with tag
+func (u URL) HasTag(tag string) bool {
+	for _, t := range u.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
 // Good return true if last request was successfull
 func (u URL) Good() bool {
 	return u.Err == "" && u.Status == http.StatusOK
 }
 
-func getURL(id int, link string, db *sql.DB) *URL {
+// parseURL parses url definition in format:
+// link [name=value] [group=value] [tags=tag,...] [severity=value] [parent=link_or_name,...]
+func parseURL(id int, line string) (*URL, error) {
+	fields := strings.Fields(line)
 	url := &URL{
 		id:        id,
-		Link:      link,
+		Link:      fields[0],
+		Name:      fields[0],
+		Severity:  SeverityError,
 		lastCheck: time.Now()}
+	for _, option := range fields[1:] {
+		parts := strings.SplitN(option, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return nil, errors.New("invalid option " + option)
+		}
+		switch parts[0] {
+		case "name":
+			url.Name = parts[1]
+		case "group":
+			url.Group = parts[1]
+		case "tags":
+			url.Tags = append(url.Tags, strings.Split(parts[1], ",")...)
+		case "severity":
+			if !containsAny(severities, []string{parts[1]}) {
+				return nil, errors.New("unknown severity " + parts[1])
+			}
+			url.Severity = parts[1]
+		case "parent":
+			url.Parents = append(url.Parents, strings.Split(parts[1], ",")...)
+		default:
+			return nil, errors.New("unknown option " + parts[0])
+		}
+	}
+	return url, nil
+}
+
+func (u *URL) load(db *sql.DB) {
+	var downSince, ackedAt sql.NullTime
 	err := db.QueryRow(
-		"SELECT last_change, hash, status, error FROM urls WHERE link=?;", url.Link,
-	).Scan(&url.LastChange, &url.hash, &url.Status, &url.Err)
+		"SELECT last_change, hash, status, error, unreachable_via, down_since, acked_by, acked_at FROM urls WHERE link=?;",
+		u.Link,
+	).Scan(&u.LastChange, &u.hash, &u.Status, &u.Err, &u.UnreachableVia, &downSince, &u.AckedBy, &ackedAt)
+	u.DownSince, u.AckedAt = downSince.Time, ackedAt.Time
+	if err == nil && !u.Good() && u.DownSince.IsZero() {
+		// saved before down_since was tracked
+		u.DownSince = u.LastChange
+	}
 	if err != nil {
 		if err == sql.ErrNoRows {
-			url.Update()
-			err = url.save(db)
+			u.Update()
+			err = u.save(db)
 			if err != nil {
 				log.Fatal().Err(err).Msg("Failed to save url to DB")
 			}
@@ -128,7 +232,6 @@ func getURL(id int, link string, db *sql.DB) *URL {
 
 		}
 	}
-	return url
 }
 
 // URLUpdate contains information about url changes
@@ -137,14 +240,49 @@ type URLUpdate struct {
 	Old     URL
 	Changed []int
 	Created time.Time
+	// Dependents contains links of urls depending on updated one
+	Dependents []string
+	// Reminder is number of repeated notification of url which is still down,
+	// it's zero for changes
+	Reminder int
+	// Escalation is number of escalation step notified by update, zero for changes
+	Escalation int
+}
+
+// ChangeNames returns names of update changes: status, content, error
+func (u URLUpdate) ChangeNames() []string {
+	names := make([]string, 0, len(u.Changed))
+	for _, change := range u.Changed {
+		for name, value := range changeNames {
+			if value == change {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// Acked checks if update acknowledges incident of url
+func (u URLUpdate) Acked() bool {
+	for _, change := range u.Changed {
+		if change == AckChange {
+			return true
+		}
+	}
+	return false
 }
 
 // Error return error description
 func (u URLUpdate) Error() *string {
+	return u.LocalizedError(Locale{Language: DefaultLanguage})
+}
+
+// LocalizedError return error description translated to locale language
+func (u URLUpdate) LocalizedError(l Locale) *string {
 	if u.New.Err != "" {
 		return &u.New.Err
 	} else if u.New.Status != http.StatusOK {
-		errText := strconv.Itoa(u.New.Status) + " status"
+		errText := l.T("status", u.New.Status)
 		return &errText
 	}
 	return nil
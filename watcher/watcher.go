@@ -2,6 +2,8 @@ package watcher
 
 import (
 	"database/sql"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,19 +17,51 @@ type Notifier interface {
 
 // Watcher check if urls changed
 type Watcher struct {
+	// urls states are changed only by watcher loop under urlMux,
+	// other goroutines read copies from GetUrls
 	urls        []*URL
+	urlMux      sync.RWMutex
 	period      time.Duration
 	errorPeriod time.Duration
 	dbPath      string
 	db          *sql.DB
+	locale      Locale
+	outbox      OutboxConfig
+
+	deliveryFailures map[string]int
+	deliveryMux      sync.Mutex
+
+	maintenance    []*Maintenance
+	maintenanceMux sync.RWMutex
+
+	// parentSuppressed marks urls which failure wasn't reported due to parent
+	parentSuppressed map[int]bool
+	// deferred contains failures of urls waiting for recheck of their parents
+	deferred map[int]URLUpdate
+
+	policies      []*EscalationPolicy
+	escalations   map[string]*Escalation
+	escalationMux sync.Mutex
+
+	ackSecret []byte
+	acks      chan ackRequest
+
+	digests          []*DigestConfig
+	historyRetention time.Duration
 }
 
 // Start watcher as daemon
 func (w *Watcher) Start(notifiers []Notifier) {
+	w.checkNotifiers(notifiers)
 	checking := make(map[int]bool)
-	updates := make(chan URLUpdate)
+	silenced := make(map[int]silence)
+	results := make(chan checkResult)
 	ticker := time.NewTicker(100 * time.Microsecond)
 	defer ticker.Stop()
+	maintenanceTicker := time.NewTicker(time.Second)
+	defer maintenanceTicker.Stop()
+	cleanupTicker := time.NewTicker(time.Hour)
+	defer cleanupTicker.Stop()
 	for {
 		select {
 		case <-ticker.C:
@@ -44,26 +78,97 @@ func (w *Watcher) Start(notifiers []Notifier) {
 					}
 					checking[url.id] = true
 					log.Debug().Str("url", url.Link).Msg("Found url to check")
-					go w.check(url, updates)
+					go w.check(url, results)
 				}
 			}
-		case update := <-updates:
+		case result := <-results:
+			update := w.apply(result)
 			log.Debug().Str("url", update.New.Link).Msg("Checked")
 			delete(checking, update.Old.id)
-			if len(update.Changed) > 0 {
-				for _, n := range notifiers {
-					go n.Notify(update)
+			w.releaseDeferred(notifiers)
+			if len(update.Changed) == 0 {
+				continue
+			}
+			if m := w.inMaintenance(update.New, update.Created); m != nil {
+				if _, ok := silenced[update.Old.id]; !ok {
+					silenced[update.Old.id] = silence{url: w.getURLByID(update.Old.id), old: update.Old, window: m}
 				}
+				log.Info().Str("url", update.New.Link).Str("maintenance", m.Name).Msg("Notification silenced")
+				continue
+			}
+			if w.suppressedByParent(&update) {
+				log.Info().Str("url", update.New.Link).Str("parent", update.Old.UnreachableVia+update.New.UnreachableVia).
+					Msg("Notification suppressed by parent")
+				continue
+			}
+			if w.deferToParents(update) {
+				log.Debug().Str("url", update.New.Link).Msg("Notification deferred until parents are checked")
+				continue
 			}
+			update.Dependents = w.dependents(update.Old.id)
+			w.notify(notifiers, update)
+		case <-maintenanceTicker.C:
+			w.checkMaintenanceEnd(silenced, notifiers)
+			w.checkEscalations(notifiers)
+			w.checkDigests(notifiers)
+		case req := <-w.acks:
+			result := w.acknowledge(req)
+			req.reply <- result
+			if result.update != nil {
+				w.notify(notifiers, *result.update)
+			}
+		case <-cleanupTicker.C:
+			w.cleanOutbox()
+			w.cleanDeliveries()
+			w.cleanHistory()
+		}
+	}
+}
+
+func (w *Watcher) notify(notifiers []Notifier, update URLUpdate) {
+	allowed := w.escalationFilter(update)
+	for _, n := range notifiers {
+		if m, ok := n.(Matcher); ok && !m.Match(update) {
+			continue
+		}
+		if !allowed(n) {
+			continue
+		}
+		go n.Notify(update)
+	}
+}
+
+func (w *Watcher) getURLByID(id int) *URL {
+	for _, url := range w.urls {
+		if url.id == id {
+			return url
+		}
+	}
+	return nil
+}
+
+func (w *Watcher) getURLByLink(link string) *URL {
+	for _, url := range w.urls {
+		if url.Link == link {
+			return url
 		}
 	}
+	return nil
 }
 
-func (w *Watcher) check(url *URL, out chan<- URLUpdate) {
+func (w *Watcher) check(url *URL, out chan<- checkResult) {
 	log.Debug().Str("url", url.Link).Msg("Got url to check")
-	update := url.Update()
+	out <- url.fetch()
+}
+
+// apply changes url state by check result, it's called by watcher loop only
+func (w *Watcher) apply(r checkResult) URLUpdate {
+	w.urlMux.Lock()
+	update := r.url.change(r.hash, r.status, r.responseTime, r.err)
+	w.urlMux.Unlock()
 	update.New.save(w.db)
-	out <- update
+	w.recordCheck(update)
+	return update
 }
 
 func (w *Watcher) initDB() {
@@ -84,29 +189,207 @@ func (w *Watcher) initDB() {
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to create table")
 	}
+	addColumn(db, "urls", "unreachable_via", "VARCHAR(200) NOT NULL DEFAULT ''")
+	addColumn(db, "urls", "down_since", "DATE")
+	addColumn(db, "urls", "acked_by", "VARCHAR(200) NOT NULL DEFAULT ''")
+	addColumn(db, "urls", "acked_at", "DATE")
+	_, err = db.Exec(
+		`CREATE TABLE IF NOT EXISTS maintenance (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name VARCHAR(200) NOT NULL,
+			start DATE NOT NULL,
+			schedule VARCHAR(100) NOT NULL,
+			duration INT NOT NULL,
+			urls TEXT NOT NULL,
+			alert_after BOOLEAN NOT NULL
+		);`,
+	)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create maintenance table")
+	}
+	addColumn(db, "maintenance", "tags", "TEXT NOT NULL DEFAULT '[]'")
+	_, err = db.Exec(
+		`CREATE TABLE IF NOT EXISTS outbox (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			notifier VARCHAR(200) NOT NULL,
+			updates TEXT NOT NULL,
+			status VARCHAR(20) NOT NULL,
+			attempts INT NOT NULL,
+			last_error TEXT NOT NULL,
+			created DATE NOT NULL,
+			next_attempt DATE NOT NULL
+		);`,
+	)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create outbox table")
+	}
+	addColumn(db, "outbox", "target", "VARCHAR(200) NOT NULL DEFAULT ''")
+	_, err = db.Exec("CREATE INDEX IF NOT EXISTS outbox_pending ON outbox (notifier, status, next_attempt);")
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create outbox index")
+	}
+	_, err = db.Exec(
+		`CREATE TABLE IF NOT EXISTS deliveries (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			notifier VARCHAR(200) NOT NULL,
+			time DATE NOT NULL,
+			target VARCHAR(500) NOT NULL,
+			payload_hash VARCHAR(64) NOT NULL,
+			response_code INT NOT NULL,
+			error TEXT NOT NULL,
+			latency INT NOT NULL
+		);`,
+	)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create deliveries table")
+	}
+	_, err = db.Exec(
+		`CREATE TABLE IF NOT EXISTS escalations (
+			link VARCHAR(200) PRIMARY KEY,
+			policy VARCHAR(200) NOT NULL,
+			started DATE NOT NULL,
+			step INT NOT NULL
+		);`,
+	)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create escalations table")
+	}
+	_, err = db.Exec(
+		`CREATE TABLE IF NOT EXISTS stats (
+			link VARCHAR(200) NOT NULL,
+			hour DATE NOT NULL,
+			checks INT NOT NULL,
+			response_total INT NOT NULL,
+			response_max INT NOT NULL,
+			content_changes INT NOT NULL,
+			PRIMARY KEY (link, hour)
+		);`,
+	)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create stats table")
+	}
+	_, err = db.Exec(
+		`CREATE TABLE IF NOT EXISTS incidents (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			link VARCHAR(200) NOT NULL,
+			started DATE NOT NULL,
+			ended DATE
+		);`,
+	)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create incidents table")
+	}
 	w.db = db
 }
 
-// GetUrls return watchers urls slice
-func (w Watcher) GetUrls() []*URL {
-	return w.urls
+// addColumn adds column to existing table if it's missing
+func addColumn(db *sql.DB, table, column, definition string) {
+	rows, err := db.Query("PRAGMA table_info(" + table + ");")
+	if err != nil {
+		log.Fatal().Err(err).Str("table", table).Msg("Failed to get table info")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			name, typ        string
+			dflt             sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			log.Fatal().Err(err).Str("table", table).Msg("Failed to read table info")
+		}
+		if name == column {
+			return
+		}
+	}
+	_, err = db.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition + ";")
+	if err != nil {
+		log.Fatal().Err(err).Str("table", table).Str("column", column).Msg("Failed to add column")
+	}
+}
+
+// Locale returns default locale of notifiers
+func (w *Watcher) Locale() Locale {
+	return w.locale
+}
+
+// GetUrls returns copies of urls states
+func (w *Watcher) GetUrls() []URL {
+	w.urlMux.RLock()
+	defer w.urlMux.RUnlock()
+	urls := make([]URL, 0, len(w.urls))
+	for _, url := range w.urls {
+		urls = append(urls, *url)
+	}
+	return urls
+}
+
+// GetTags returns sorted list of all url tags
+func (w *Watcher) GetTags() []string {
+	tags := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, url := range w.urls {
+		for _, tag := range url.Tags {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
 }
 
 // NewWatcher returns watcher
-func NewWatcher(urls []string, cfg Config) Watcher {
-	watcher := Watcher{
+func NewWatcher(urls []string, cfg Config) *Watcher {
+	watcher := &Watcher{
 		period:      cfg.Period * time.Second,
 		errorPeriod: cfg.ErrorPeriod * time.Second,
-		dbPath:      cfg.DBPath}
+		dbPath:      cfg.DBPath,
+		outbox:      cfg.Outbox,
+
+		deliveryFailures: make(map[string]int),
+		parentSuppressed: make(map[int]bool),
+		deferred:         make(map[int]URLUpdate),
+		escalations:      make(map[string]*Escalation),
+		ackSecret:        []byte(cfg.AckSecret),
+		acks:             make(chan ackRequest),
+		historyRetention: cfg.HistoryRetention * time.Second}
+	locale, err := NewLocale(cfg.Language, cfg.Timezone)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Invalid locale settings")
+	}
+	watcher.locale = locale
 	watcher.initDB()
+	watcher.initMaintenance(cfg.Maintenance)
+	watcher.initEscalations(cfg.Escalations)
+	watcher.initDigests(cfg.Digests)
+	for _, line := range urls {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		url, err := parseURL(len(watcher.urls), line)
+		if err != nil {
+			log.Fatal().Err(err).Str("line", line).Msg("Invalid url definition")
+		}
+		watcher.urls = append(watcher.urls, url)
+	}
 	var wg sync.WaitGroup
-	wg.Add(len(urls))
-	for idx, url := range urls {
-		go func(idx int, url string) {
+	wg.Add(len(watcher.urls))
+	for _, url := range watcher.urls {
+		go func(url *URL) {
 			defer wg.Done()
-			watcher.urls = append(watcher.urls, getURL(idx, url, watcher.db))
-		}(idx, url)
+			url.load(watcher.db)
+		}(url)
 	}
 	wg.Wait()
+	watcher.resolveParents()
+	watcher.pruneEscalations()
+	for _, url := range watcher.urls {
+		if url.UnreachableVia != "" {
+			watcher.parentSuppressed[url.id] = true
+		}
+	}
 	return watcher
 }